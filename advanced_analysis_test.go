@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"testing"
 	"time"
-	"github.com/ncw/gmp"
 )
 
 // TestCycleDetection analyzes if values other than 0 and 1 have short cycles
@@ -18,7 +17,7 @@ func TestCycleDetection(t *testing.T) {
 	
 	for _, val := range testValues {
 		fmt.Printf("\nTesting value %d:\n", val)
-		x := gmp.NewInt(val)
+		x := NewInt(val)
 		seen := make(map[string]int)
 		
 		for i := 0; i < maxIterations; i++ {
@@ -45,12 +44,12 @@ func TestCycleDetection(t *testing.T) {
 
 // advancedSolveWithCycleDetection implements cycle detection optimization
 func (c *Challenge) advancedSolveWithCycleDetection() string {
-	x := gmp.NewInt(0).Set(c.x) // don't mutate c.x
+	x := NewInt(0).Set(c.x) // don't mutate c.x
 	
 	// Fast path for known edge cases
 	if x.Sign() == 0 {
 		if c.d%2 == 0 {
-			return fmt.Sprintf("%s.%s", version, base64.StdEncoding.EncodeToString(gmp.NewInt(0).Bytes()))
+			return fmt.Sprintf("%s.%s", version, base64.StdEncoding.EncodeToString(NewInt(0).Bytes()))
 		} else {
 			return fmt.Sprintf("%s.%s", version, base64.StdEncoding.EncodeToString(one.Bytes()))
 		}
@@ -60,7 +59,7 @@ func (c *Challenge) advancedSolveWithCycleDetection() string {
 		if c.d%2 == 0 {
 			return fmt.Sprintf("%s.%s", version, base64.StdEncoding.EncodeToString(one.Bytes()))
 		} else {
-			return fmt.Sprintf("%s.%s", version, base64.StdEncoding.EncodeToString(gmp.NewInt(0).Bytes()))
+			return fmt.Sprintf("%s.%s", version, base64.StdEncoding.EncodeToString(NewInt(0).Bytes()))
 		}
 	}
 	
@@ -104,7 +103,7 @@ func TestAdvancedOptimizations(t *testing.T) {
 	fmt.Printf("Testing challenge with d=%d\n", c.d)
 	
 	// Test cycle detection (with smaller difficulty for demonstration)
-	smallC := &Challenge{d: 100, x: gmp.NewInt(0).Set(c.x)}
+	smallC := &Challenge{d: 100, x: NewInt(0).Set(c.x)}
 	
 	start := time.Now()
 	regularResult := smallC.Solve()
@@ -185,7 +184,7 @@ func BenchmarkSpecificChallengeComparison(b *testing.B) {
 	}
 	
 	// Use smaller difficulty for benchmarking to avoid timeouts
-	smallC := &Challenge{d: 50, x: gmp.NewInt(0).Set(c.x)}
+	smallC := &Challenge{d: 50, x: NewInt(0).Set(c.x)}
 	
 	b.Run("Current_Optimized", func(b *testing.B) {
 		b.ResetTimer()
@@ -0,0 +1,279 @@
+package pow
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SolveResult is the outcome of SolveAsync.
+type SolveResult struct {
+	Solution string        // empty if Err is set
+	Elapsed  time.Duration // wall time spent solving
+	Err      error         // an *IncompleteError if the solve was cancelled
+}
+
+// SolveAsync solves c in a new goroutine and returns a channel that delivers
+// the result and is then closed. The solve stops once ctx is done, so a
+// caller that gives up can cancel ctx instead of leaving the computation
+// running.
+func (c *Challenge) SolveAsync(ctx context.Context) <-chan SolveResult {
+	ch := make(chan SolveResult, 1)
+	go func() {
+		defer close(ch)
+		start := time.Now()
+		x, n, err := c.solveTimed(ctx, NewInt(0))
+		r := SolveResult{Elapsed: time.Since(start)}
+		if err != nil {
+			r.Err = c.incomplete(err, n, x)
+		} else {
+			r.Solution = c.params().encodeSolution(x)
+		}
+		ch <- r
+	}()
+	return ch
+}
+
+// SolveAll solves cs on a pool of workers goroutines, or GOMAXPROCS of them
+// if workers is not positive, and returns the solutions in the same order.
+// Once ctx is done no new solves start and running ones stop; SolveAll then
+// returns the solutions that did finish, with empty strings for the rest,
+// and ctx.Err().
+func SolveAll(ctx context.Context, cs []*Challenge, workers int) ([]string, error) {
+	return SolveAllProgress(ctx, cs, workers, nil)
+}
+
+// SolveAllProgress is like SolveAll but calls progress, if non-nil, each time
+// a challenge is solved, with the number solved so far and len(cs). The calls
+// are made one at a time from the worker goroutines.
+func SolveAllProgress(ctx context.Context, cs []*Challenge, workers int, progress func(done, total int)) ([]string, error) {
+	solutions := make([]string, len(cs))
+	var (
+		mu   sync.Mutex
+		done int
+	)
+	runPool(ctx, len(cs), workers, func(i int, x *Int) {
+		c := cs[i]
+		if _, _, err := c.solveTimed(ctx, x); err != nil {
+			return
+		}
+		solutions[i] = c.params().encodeSolution(x)
+		mu.Lock()
+		done++
+		if progress != nil {
+			progress(done, len(cs))
+		}
+		mu.Unlock()
+	})
+	if done < len(cs) {
+		return solutions, ctx.Err()
+	}
+	return solutions, nil
+}
+
+// A Submission is a challenge and a proposed solution, as strings.
+type Submission struct {
+	Challenge string
+	Solution  string
+}
+
+// CheckResult is the outcome of checking one Submission, or of
+// CheckDetailed.
+type CheckResult struct {
+	OK     bool
+	Err    error  // from decoding the challenge or Check, or ctx.Err()
+	Reason Reason // classifies OK and Err
+}
+
+// CheckAll checks subs on a pool of workers goroutines, or GOMAXPROCS of them
+// if workers is not positive, and returns the results in the same order.
+// Each challenge is decoded with DecodeChallenge and checked with
+// CheckContext. Once ctx is done the checks stop, and the submissions left
+// unchecked report ctx.Err().
+func CheckAll(ctx context.Context, subs []Submission, workers int) []CheckResult {
+	results := make([]CheckResult, len(subs))
+	checked := make([]bool, len(subs))
+	runPool(ctx, len(subs), workers, func(i int, _ *Int) {
+		c, err := DecodeChallenge(subs[i].Challenge)
+		if err == nil {
+			results[i].OK, err = c.CheckContext(ctx, subs[i].Solution)
+		}
+		results[i].Err = err
+		checked[i] = true
+	})
+	for i := range results {
+		if !checked[i] {
+			results[i].Err = ctx.Err()
+		}
+		results[i].Reason = ReasonOf(results[i].OK, results[i].Err)
+	}
+	return results
+}
+
+// CheckStream checks submissions read from r, one per line as a challenge
+// and a solution separated by a tab, on a pool of workers goroutines, or
+// GOMAXPROCS of them if workers is not positive. Each line is written back
+// to w in input order, followed by a tab and "ok", "invalid", or "error: "
+// and the error. Blank lines are skipped. Only a few lines per worker are
+// held at a time, so logs of any size can be audited.
+//
+// It returns the first error reading r or writing w, or ctx.Err() if ctx is
+// done first, in which case the output stops at the first unchecked line.
+// Lines may hold checkpoint proofs of up to MaxCheckpoints values over the
+// built-in fields; a longer line ends the stream with bufio.ErrTooLong.
+func CheckStream(ctx context.Context, r io.Reader, w io.Writer, workers int) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The reader queues each line's result channel in pending, in order,
+	// before handing the line to the workers. It is not waited for, since
+	// it cannot be interrupted while blocked reading r; once ctx is done it
+	// returns by itself as soon as the read does.
+	type job struct {
+		line string
+		out  chan string
+	}
+	jobs := make(chan job)
+	pending := make(chan chan string, 4*workers)
+	var wg sync.WaitGroup
+	for n := 0; n < workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case j, ok := <-jobs:
+					if !ok {
+						return
+					}
+					j.out <- j.line + "\t" + checkLine(ctx, j.line)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	var readErr error // only read once pending is closed
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, maxStreamLine())
+		for sc.Scan() {
+			line := strings.TrimSuffix(sc.Text(), "\r")
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			j := job{line, make(chan string, 1)}
+			select {
+			case pending <- j.out:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- j:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr = sc.Err()
+	}()
+
+	err := func() error {
+		for {
+			var out chan string
+			var ok bool
+			select {
+			case out, ok = <-pending:
+				if !ok {
+					return ctx.Err()
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			select {
+			case res := <-out:
+				if _, err := io.WriteString(w, res+"\n"); err != nil {
+					return err
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}()
+	cancel()
+	wg.Wait()
+	if err == nil {
+		err = readErr
+	}
+	return err
+}
+
+// maxStreamLine returns the longest line CheckStream reads: a challenge and
+// a checkpoint proof of MaxCheckpoints values, each as long as a value of
+// ParamsP2203, the largest built-in field, with room for the prefixes.
+func maxStreamLine() int {
+	value := base64.StdEncoding.EncodedLen(ParamsP2203.format().MaxValueBytes) + 1
+	return (MaxCheckpoints+2)*value + 1024
+}
+
+// checkLine checks a CheckStream line and describes the outcome.
+func checkLine(ctx context.Context, line string) string {
+	i := strings.IndexByte(line, '\t')
+	if i < 0 {
+		return "error: no tab between challenge and solution"
+	}
+	c, err := DecodeChallenge(line[:i])
+	var ok bool
+	if err == nil {
+		ok, err = c.CheckContext(ctx, line[i+1:])
+	}
+	switch {
+	case err != nil:
+		return "error: " + err.Error()
+	case ok:
+		return "ok"
+	}
+	return "invalid"
+}
+
+// runPool calls fn for each index below n on up to workers goroutines, or
+// GOMAXPROCS if workers is not positive, and waits for the calls to return.
+// Each goroutine passes fn its own scratch Int. Once ctx is done no more
+// calls start.
+func runPool(ctx context.Context, n, workers int, fn func(i int, x *Int)) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			x := NewInt(0)
+			for i := range jobs {
+				fn(i, x)
+			}
+		}()
+	}
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
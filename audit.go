@@ -0,0 +1,76 @@
+package pow
+
+import (
+	"strconv"
+	"time"
+)
+
+// An AuditEventType says what an AuditEvent records.
+type AuditEventType int
+
+const (
+	// ChallengeIssued is a challenge handed out by Server.Issue.
+	ChallengeIssued AuditEventType = iota
+	// SolutionAccepted is a solution that passed verification.
+	SolutionAccepted
+	// SolutionRejected is a solution that did not, for the reason in Err.
+	SolutionRejected
+)
+
+func (t AuditEventType) String() string {
+	switch t {
+	case ChallengeIssued:
+		return "challenge_issued"
+	case SolutionAccepted:
+		return "solution_accepted"
+	case SolutionRejected:
+		return "solution_rejected"
+	}
+	return "AuditEventType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// An AuditEvent describes one issuance or verification by Server or
+// Verifier.
+type AuditEvent struct {
+	Type      AuditEventType
+	Time      time.Time
+	Challenge string // as issued, or as submitted
+	// Difficulty is the challenge's difficulty, or 0 for rejected
+	// challenges that could not be decoded.
+	Difficulty uint32
+	// Err is why a solution was rejected, such as ErrUnknownChallenge or a
+	// decoding error. It is nil for the other events and for solutions
+	// that were well-formed but wrong.
+	Err error
+	// Reason classifies the outcome of a verification. It is
+	// ReasonAccepted for issued challenges.
+	Reason Reason
+}
+
+// An Auditor receives the AuditEvents of the types it is installed in, for
+// shipping to a log or SIEM. Audit is called synchronously on the path of
+// the request, so it should hand events off rather than block, and must be
+// safe for concurrent use.
+type Auditor interface {
+	Audit(AuditEvent)
+}
+
+// AuditorFunc adapts a function to an Auditor.
+type AuditorFunc func(AuditEvent)
+
+// Audit calls f.
+func (f AuditorFunc) Audit(e AuditEvent) {
+	f(e)
+}
+
+// auditCheck reports the outcome of checking a solution to a, if it is set.
+func auditCheck(a Auditor, challenge string, d uint32, ok bool, err error) {
+	if a == nil {
+		return
+	}
+	e := AuditEvent{Type: SolutionRejected, Time: now(), Challenge: challenge, Difficulty: d, Err: err, Reason: ReasonOf(ok, err)}
+	if ok && err == nil {
+		e.Type = SolutionAccepted
+	}
+	a.Audit(e)
+}
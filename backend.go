@@ -0,0 +1,61 @@
+package pow
+
+import (
+	"sync"
+	"time"
+)
+
+// kernel is one implementation of the iterations over 2^1279-1. All kernels
+// compute the same values; they differ only in speed.
+type kernel struct {
+	name    string
+	iterate func(x *Int, n uint32)
+}
+
+// kernels holds the candidates for the default field. Files for particular
+// CPUs append to it in init when the CPU supports their kernel.
+var kernels = []kernel{
+	{expBackend, ParamsP1279.iterateExp},
+	{"go", iterate1279Generic},
+}
+
+// kernelTrials is how many iterations each kernel runs when they are timed.
+// The fastest of them takes well under a millisecond per iteration.
+const kernelTrials = 4
+
+var (
+	kernelOnce sync.Once
+	kernel1279 kernel
+)
+
+// Backend returns the name of the arithmetic used to solve challenges over
+// the default field: "gmp" or "math/big" for generic exponentiation with the
+// backend's Int, "go" for the fixed-width Go kernel, or "avx512ifma" for the
+// amd64 IFMA kernel. The candidates the CPU supports are timed against each
+// other the first time one is needed, and the fastest is used from then on.
+// Other parameters always use generic exponentiation.
+func Backend() string {
+	return selectKernel().name
+}
+
+func selectKernel() kernel {
+	kernelOnce.Do(func() {
+		kernel1279 = fastestKernel(kernels)
+	})
+	return kernel1279
+}
+
+func fastestKernel(ks []kernel) kernel {
+	best, bestTime := ks[0], time.Duration(-1)
+	x := NewInt(0)
+	for _, k := range ks {
+		x.Lsh(one, 1278)
+		x.Add(x, two)
+		start := time.Now()
+		k.iterate(x, kernelTrials)
+		if t := time.Since(start); bestTime < 0 || t < bestTime {
+			best, bestTime = k, t
+		}
+	}
+	return best
+}
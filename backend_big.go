@@ -0,0 +1,32 @@
+//go:build !cgo || purego
+
+package pow
+
+import "math/big"
+
+// Int is the arbitrary-precision integer type of the arithmetic backend. It
+// is gmp.Int from github.com/ncw/gmp by default, and math/big.Int when built
+// with the purego tag or without cgo. Both produce identical results.
+type Int = big.Int
+
+// NewInt allocates and returns a new Int set to x.
+func NewInt(x int64) *Int {
+	return big.NewInt(x)
+}
+
+// expBackend names the generic Exp path in Backend.
+const expBackend = "math/big"
+
+// appendValueBytes appends the big-endian bytes of x, with no leading zeros,
+// to dst.
+func appendValueBytes(dst []byte, x *Int) []byte {
+	n := len(dst)
+	dst = append(dst, make([]byte, (x.BitLen()+7)/8)...)
+	x.FillBytes(dst[n:])
+	return dst
+}
+
+// jacobi returns the Jacobi symbol (x/y) for odd y.
+func jacobi(x, y *Int) int {
+	return big.Jacobi(x, y)
+}
@@ -0,0 +1,34 @@
+//go:build cgo && !purego
+
+package pow
+
+import (
+	"math/big"
+
+	"github.com/ncw/gmp"
+)
+
+// Int is the arbitrary-precision integer type of the arithmetic backend. It
+// is gmp.Int from github.com/ncw/gmp by default, and math/big.Int when built
+// with the purego tag or without cgo. Both produce identical results.
+type Int = gmp.Int
+
+// NewInt allocates and returns a new Int set to x.
+func NewInt(x int64) *Int {
+	return gmp.NewInt(x)
+}
+
+// expBackend names the generic Exp path in Backend.
+const expBackend = "gmp"
+
+// appendValueBytes appends the big-endian bytes of x, with no leading zeros,
+// to dst. gmp has no way to export into a caller's buffer, so this allocates.
+func appendValueBytes(dst []byte, x *Int) []byte {
+	return append(dst, x.Bytes()...)
+}
+
+// jacobi returns the Jacobi symbol (x/y) for odd y. gmp.Int does not provide
+// it, so the values go through math/big.
+func jacobi(x, y *Int) int {
+	return big.Jacobi(new(big.Int).SetBytes(x.Bytes()), new(big.Int).SetBytes(y.Bytes()))
+}
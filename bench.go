@@ -0,0 +1,33 @@
+package pow
+
+import (
+	"context"
+	"time"
+)
+
+// BenchResult is the outcome of Benchmark.
+type BenchResult struct {
+	Solution   string        // empty if the solve did not finish
+	Iterations uint32        // iterations performed
+	Elapsed    time.Duration // wall time spent solving
+}
+
+// Rate returns the effective number of iterations per second.
+func (r BenchResult) Rate() float64 {
+	return SolveStats{Iterations: r.Iterations, Elapsed: r.Elapsed}.Rate()
+}
+
+// Benchmark solves c and reports how long it took. The solve stops once ctx
+// is done, in which case the result describes the partial run and the error
+// is ctx.Err(). Use context.WithTimeout to bound how long a benchmark may
+// take.
+func Benchmark(ctx context.Context, c *Challenge) (BenchResult, error) {
+	start := time.Now()
+	x, n, err := c.solveTimed(ctx, NewInt(0))
+	r := BenchResult{Iterations: n, Elapsed: time.Since(start)}
+	if err != nil {
+		return r, err
+	}
+	r.Solution = c.params().encodeSolution(x)
+	return r, nil
+}
@@ -0,0 +1,30 @@
+package pow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBenchmark(t *testing.T) {
+	c := &Challenge{d: 10, x: NewInt(12345)}
+	r, err := Benchmark(context.Background(), c)
+	if err != nil {
+		t.Fatalf("Benchmark failed: %v", err)
+	}
+	if r.Solution != c.solveOriginal() || r.Iterations != 10 || r.Elapsed <= 0 || r.Rate() <= 0 {
+		t.Errorf("Benchmark = %+v", r)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	c = &Challenge{d: 1 << 30, x: NewInt(12345)}
+	r, err = Benchmark(ctx, c)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Benchmark error = %v, want context.DeadlineExceeded", err)
+	}
+	if r.Solution != "" || r.Iterations >= c.d {
+		t.Errorf("timed out Benchmark = %+v, want a partial result", r)
+	}
+}
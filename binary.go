@@ -0,0 +1,65 @@
+package pow
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The binary form of a challenge is
+//
+//	version (1 byte) || difficulty (4 bytes) || len(x) (2 bytes) || x
+//
+// with all integers big-endian and x in its minimal big-endian bytes. The
+// version byte is the index of the parameters in binaryParams.
+const binaryHeaderSize = 1 + 4 + 2
+
+// binaryParams maps binary version bytes to parameters. Index 0 is unused so
+// that a zeroed buffer never decodes.
+var binaryParams = []*Params{nil, ParamsP1279, ParamsP2203}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (c *Challenge) MarshalBinary() ([]byte, error) {
+	p := c.params()
+	v := -1
+	for i, bp := range binaryParams {
+		if bp == p && bp != nil {
+			v = i
+		}
+	}
+	if v < 0 {
+		return nil, fmt.Errorf("%w: no binary version for %q", ErrBadVersion, p.Version)
+	}
+	x := c.value().Bytes()
+	b := make([]byte, binaryHeaderSize, binaryHeaderSize+len(x))
+	b[0] = byte(v)
+	binary.BigEndian.PutUint32(b[1:], c.d)
+	binary.BigEndian.PutUint16(b[5:], uint16(len(x)))
+	return append(b, x...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler for the encoding
+// produced by MarshalBinary.
+func (c *Challenge) UnmarshalBinary(data []byte) error {
+	if len(data) < binaryHeaderSize {
+		return fmt.Errorf("%w: binary challenge too short", ErrBadEncoding)
+	}
+	if data[0] == 0 || int(data[0]) >= len(binaryParams) {
+		return fmt.Errorf("%w %d", ErrBadVersion, data[0])
+	}
+	p := binaryParams[data[0]]
+	n := int(binary.BigEndian.Uint16(data[5:]))
+	if len(data) != binaryHeaderSize+n {
+		return fmt.Errorf("%w: binary challenge length mismatch", ErrBadEncoding)
+	}
+	x := data[binaryHeaderSize:]
+	decoded, err := p.newChallenge(binary.BigEndian.Uint32(data[1:]), x, MaxCheckDifficulty)
+	if err != nil {
+		return err
+	}
+	// MarshalBinary writes x without leading zeros
+	if RequireCanonicalChallenges && len(x) > 0 && x[0] == 0 {
+		return ErrNonCanonicalChallenge
+	}
+	*c = *decoded
+	return nil
+}
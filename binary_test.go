@@ -0,0 +1,63 @@
+package pow
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	defer func(old uint32) { MaxCheckDifficulty = old }(MaxCheckDifficulty)
+	MaxCheckDifficulty = 1<<32 - 1
+	challenges := []*Challenge{
+		{},
+		{d: 1<<32 - 1, x: NewInt(255)},
+		{d: 7, x: NewInt(0).Sub(mod, one)},
+		{d: 7, x: NewInt(0).Sub(ParamsP2203.Modulus, one), p: ParamsP2203},
+		GenerateChallenge(90000),
+	}
+	for _, c := range challenges {
+		b, err := c.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary(%s): %v", c, err)
+		}
+		var got Challenge
+		if err := got.UnmarshalBinary(b); err != nil {
+			t.Fatalf("UnmarshalBinary(%x): %v", b, err)
+		}
+		if got.String() != c.String() {
+			t.Errorf("binary round trip of %s = %s", c, &got)
+		}
+	}
+
+	c := GenerateChallenge(90000)
+	b, _ := c.MarshalBinary()
+	if want := binaryHeaderSize + len(c.ValueBytes()); len(b) != want {
+		t.Errorf("binary challenge is %d bytes, want %d", len(b), want)
+	}
+}
+
+func TestUnmarshalBinaryErrors(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want error
+	}{
+		{nil, ErrBadEncoding},
+		{[]byte{1, 0, 0, 0, 1, 0}, ErrBadEncoding},
+		{[]byte{0, 0, 0, 0, 1, 0, 0}, ErrBadVersion},
+		{[]byte{9, 0, 0, 0, 1, 0, 0}, ErrBadVersion},
+		{[]byte{1, 0, 0, 0, 1, 0, 2, 5}, ErrBadEncoding},
+		{[]byte{1, 0, 0, 0, 1, 0, 0, 5}, ErrBadEncoding},
+		{append([]byte{1, 0, 0, 0, 1, 0, 160}, mod.Bytes()...), ErrValueOutOfRange},
+	}
+	for _, tt := range tests {
+		var c Challenge
+		if err := c.UnmarshalBinary(tt.in); !errors.Is(err, tt.want) {
+			t.Errorf("UnmarshalBinary(%x): err = %v, want %v", tt.in, err, tt.want)
+		}
+	}
+
+	custom := &Params{Modulus: mod, Exponent: exp, Version: "custom"}
+	if _, err := custom.GenerateChallenge(1).MarshalBinary(); !errors.Is(err, ErrBadVersion) {
+		t.Errorf("MarshalBinary with custom params: err = %v, want %v", err, ErrBadVersion)
+	}
+}
@@ -0,0 +1,189 @@
+package pow
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SolutionCache remembers the solutions of recently solved challenges, for
+// deployments that present the same challenge more than once, such as ones
+// generated from a fixed seed. Challenges are keyed by their canonical
+// encoding, so a challenge matches however it was written when decoded. It is
+// safe for concurrent use.
+//
+// Concurrent solves of the same uncached challenge are not merged; each one
+// solves it and the last to finish is cached.
+type SolutionCache struct {
+	lru
+}
+
+// lru is a map of at most size entries, evicting the least recently used
+// when full and, if ttl is positive, forgetting entries ttl after they were
+// added.
+type lru struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // values are *cacheEntry
+	list    *list.List               // most recently used first
+}
+
+type cacheEntry struct {
+	key    string
+	value  interface{}
+	expiry time.Time // zero if the entry does not expire
+}
+
+func newLRU(size int, ttl time.Duration) lru {
+	if size < 1 {
+		size = 1
+	}
+	return lru{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		list:    list.New(),
+	}
+}
+
+// NewSolutionCache returns an empty SolutionCache holding at most size
+// solutions, evicting the least recently used when full. If ttl is positive,
+// solutions are forgotten ttl after they were cached.
+func NewSolutionCache(size int, ttl time.Duration) *SolutionCache {
+	return &SolutionCache{newLRU(size, ttl)}
+}
+
+// Solve is like c.Solve but returns a cached solution if there is one, and
+// caches the solution otherwise.
+func (sc *SolutionCache) Solve(c *Challenge) string {
+	s, _ := sc.SolveContext(context.Background(), c)
+	return s
+}
+
+// SolveContext is like c.SolveContext but returns a cached solution if there
+// is one, and caches the solution otherwise. Unfinished solves are not
+// cached.
+func (sc *SolutionCache) SolveContext(ctx context.Context, c *Challenge) (string, error) {
+	key := c.String()
+	if s, ok := sc.get(key); ok {
+		return s, nil
+	}
+	s, err := c.SolveContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	sc.add(key, s)
+	return s, nil
+}
+
+// Len returns the number of cached solutions, including expired ones that
+// have not been evicted yet.
+func (sc *SolutionCache) Len() int {
+	return sc.len()
+}
+
+func (sc *SolutionCache) get(key string) (string, bool) {
+	v, ok := sc.lru.get(key)
+	if !ok {
+		return "", false
+	}
+	return v.(string), true
+}
+
+// CheckCache remembers the results of recent checks, so that clients
+// resubmitting a solution, for instance on retries, cost a map lookup rather
+// than a full check. Entries are keyed by the canonical encodings of the
+// challenge and solution. It is safe for concurrent use.
+//
+// Only completed checks are cached, accepted or not. A solution that is
+// accepted stays accepted while cached, so CheckCache is no substitute for
+// CheckOnce when each challenge may be used only once.
+type CheckCache struct {
+	lru
+}
+
+// NewCheckCache returns an empty CheckCache holding at most size results,
+// evicting the least recently used when full. If ttl is positive, results
+// are forgotten ttl after they were cached.
+func NewCheckCache(size int, ttl time.Duration) *CheckCache {
+	return &CheckCache{newLRU(size, ttl)}
+}
+
+// Check is like c.Check but returns a cached result if there is one, and
+// caches the result otherwise.
+func (cc *CheckCache) Check(c *Challenge, s string) (bool, error) {
+	return cc.CheckContext(context.Background(), c, s)
+}
+
+// CheckContext is like c.CheckContext but returns a cached result if there
+// is one, and caches the result otherwise.
+func (cc *CheckCache) CheckContext(ctx context.Context, c *Challenge, s string) (bool, error) {
+	canonical, err := CanonicalSolution(s)
+	if err != nil || RequireCanonicalSolutions && strings.TrimSpace(s) != canonical {
+		// Let Check report the error
+		return c.CheckContext(ctx, s)
+	}
+	key := c.String() + " " + canonical
+	if ok, cached := cc.get(key); cached {
+		return ok.(bool), nil
+	}
+	ok, err := c.CheckContext(ctx, s)
+	if err != nil {
+		return false, err
+	}
+	cc.add(key, ok)
+	return ok, nil
+}
+
+// Len returns the number of cached results, including expired ones that
+// have not been evicted yet.
+func (cc *CheckCache) Len() int {
+	return cc.len()
+}
+
+func (l *lru) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.list.Len()
+}
+
+func (l *lru) get(key string) (interface{}, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if !e.expiry.IsZero() && now().After(e.expiry) {
+		l.list.Remove(el)
+		delete(l.entries, key)
+		return nil, false
+	}
+	l.list.MoveToFront(el)
+	return e.value, true
+}
+
+func (l *lru) add(key string, value interface{}) {
+	e := &cacheEntry{key: key, value: value}
+	if l.ttl > 0 {
+		e.expiry = now().Add(l.ttl)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.entries[key]; ok {
+		el.Value = e
+		l.list.MoveToFront(el)
+		return
+	}
+	l.entries[key] = l.list.PushFront(e)
+	for l.list.Len() > l.size {
+		el := l.list.Back()
+		l.list.Remove(el)
+		delete(l.entries, el.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,102 @@
+package pow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSolutionCache(t *testing.T) {
+	sc := NewSolutionCache(2, 0)
+	a, b, c := GenerateChallenge(3), GenerateChallenge(3), GenerateChallenge(3)
+	want := a.Solve()
+	if got := sc.Solve(a); got != want {
+		t.Fatalf("Solve = %s, want %s", got, want)
+	}
+
+	// A cached solution is returned without solving again, for any
+	// encoding of the challenge
+	d, err := DecodeChallenge(a.StringURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	SolveHook = func(SolveStats) { t.Error("cached challenge solved again") }
+	got := sc.Solve(d)
+	SolveHook = nil
+	if got != want {
+		t.Errorf("cached Solve = %s, want %s", got, want)
+	}
+
+	// a is now most recently used, so adding c evicts b
+	sc.Solve(b)
+	sc.Solve(a)
+	sc.Solve(c)
+	if n := sc.Len(); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+	if _, ok := sc.get(b.String()); ok {
+		t.Error("least recently used solution was not evicted")
+	}
+	if _, ok := sc.get(a.String()); !ok {
+		t.Error("recently used solution was evicted")
+	}
+}
+
+func TestSolutionCacheExpiry(t *testing.T) {
+	defer func() { now = time.Now }()
+	start := time.Unix(1700000000, 0)
+	now = func() time.Time { return start }
+
+	sc := NewSolutionCache(10, time.Minute)
+	c := GenerateChallenge(2)
+	sc.Solve(c)
+	if _, ok := sc.get(c.String()); !ok {
+		t.Fatal("solution not cached")
+	}
+	now = func() time.Time { return start.Add(2 * time.Minute) }
+	if _, ok := sc.get(c.String()); ok {
+		t.Error("expired solution returned")
+	}
+	if n := sc.Len(); n != 0 {
+		t.Errorf("Len = %d after expiry, want 0", n)
+	}
+}
+
+func TestCheckCache(t *testing.T) {
+	cc := NewCheckCache(10, time.Minute)
+	c := GenerateChallenge(3)
+	s := c.Solve()
+	if ok, err := cc.Check(c, s); err != nil || !ok {
+		t.Fatalf("Check = %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := cc.Check(c, "s.AA=="); err != nil || ok {
+		t.Errorf("Check with wrong solution = %v, %v; want false, nil", ok, err)
+	}
+
+	// Repeated checks are answered from the cache, for any encoding of
+	// the pair
+	d, err := DecodeChallenge(c.StringURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	PhaseHook = func(p Phase, d time.Duration) {
+		if p == PhaseIterate {
+			t.Error("cached result checked again")
+		}
+	}
+	ok, err := cc.Check(d, " "+s+"\n")
+	PhaseHook = nil
+	if err != nil || !ok {
+		t.Errorf("cached Check = %v, %v; want true, nil", ok, err)
+	}
+	if n := cc.Len(); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+
+	// Errors are not cached
+	if _, err := cc.Check(c, "garbage"); err == nil {
+		t.Error("Check of malformed solution succeeded")
+	}
+	if n := cc.Len(); n != 2 {
+		t.Errorf("Len = %d after error, want 2", n)
+	}
+}
@@ -0,0 +1,103 @@
+package pow
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// calibrationIterations is how many iterations measureThroughput solves.
+// It is enough to take a few tens of milliseconds on typical hardware.
+const calibrationIterations = 32
+
+var (
+	throughputOnce sync.Once
+	throughputBits uint64 // math.Float64bits of the cached throughput
+)
+
+// Throughput returns this machine's solving speed in iterations per second
+// for the default parameters. It is measured once per process, on first use,
+// by solving a throwaway challenge; later calls return the cached value.
+func Throughput() float64 {
+	throughputOnce.Do(func() {
+		atomic.StoreUint64(&throughputBits, math.Float64bits(measureThroughput()))
+	})
+	return math.Float64frombits(atomic.LoadUint64(&throughputBits))
+}
+
+// RecalibrateThroughput measures the solving speed again, replaces the value
+// cached by Throughput, and returns it. This is useful after the CPU
+// frequency or load has changed.
+func RecalibrateThroughput() float64 {
+	r := measureThroughput()
+	storeThroughput(r)
+	return r
+}
+
+// Calibrate runs iterations of a random challenge for about sampleDuration
+// and returns the measured speed in iterations per second. It always runs at
+// least one iteration. Longer samples give steadier figures. The result
+// replaces the value cached by Throughput, so the estimates built on it use
+// the same calibration.
+func Calibrate(sampleDuration time.Duration) (itersPerSec float64) {
+	c := GenerateChallenge(0)
+	x := NewInt(0).Set(c.value())
+	p := c.params()
+	var n uint32
+	start := time.Now()
+	for {
+		p.iterate(x, 1)
+		n++
+		if time.Since(start) >= sampleDuration {
+			break
+		}
+	}
+	r := SolveStats{Iterations: n, Elapsed: time.Since(start)}.Rate()
+	storeThroughput(r)
+	return r
+}
+
+func storeThroughput(r float64) {
+	throughputOnce.Do(func() {})
+	atomic.StoreUint64(&throughputBits, math.Float64bits(r))
+}
+
+func measureThroughput() float64 {
+	c := &Challenge{d: calibrationIterations, x: NewInt(0).Lsh(one, 127)}
+	c.x.Add(c.x, two)
+	start := time.Now()
+	c.solve(context.Background(), NewInt(0))
+	return SolveStats{Iterations: c.d, Elapsed: time.Since(start)}.Rate()
+}
+
+// EstimateSolveTime estimates how long solving a challenge of difficulty d
+// over the default parameters takes on this machine, from Throughput. Clients
+// on slower hardware, such as phones running the solver in a browser, take
+// proportionally longer; scale the estimate by their relative speed. It
+// returns 0 if no speed was measured, as when the clock is too coarse to time
+// the calibration solve.
+func EstimateSolveTime(d uint32) time.Duration {
+	r := Throughput()
+	if !(r > 0) {
+		return 0
+	}
+	return time.Duration(float64(d) / r * float64(time.Second))
+}
+
+// DifficultyForDuration returns the difficulty whose challenges take about
+// target to solve on this machine, the inverse of EstimateSolveTime. The
+// result is at least MinDifficulty, so it can be passed to
+// GenerateChallengeChecked, and at most math.MaxUint32. To aim at slower
+// clients, divide target by their speed relative to this machine first.
+func DifficultyForDuration(target time.Duration) uint32 {
+	d := math.Round(target.Seconds() * Throughput())
+	if d > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	if min := math.Max(float64(MinDifficulty), 1); d < min {
+		return uint32(min)
+	}
+	return uint32(d)
+}
@@ -0,0 +1,74 @@
+package pow
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestThroughput(t *testing.T) {
+	r := Throughput()
+	if r <= 0 {
+		t.Fatalf("Throughput() = %v, want a positive rate", r)
+	}
+	if again := Throughput(); again != r {
+		t.Errorf("second Throughput() = %v, want cached %v", again, r)
+	}
+	re := RecalibrateThroughput()
+	if re <= 0 {
+		t.Fatalf("RecalibrateThroughput() = %v, want a positive rate", re)
+	}
+	if got := Throughput(); got != re {
+		t.Errorf("Throughput() after recalibrating = %v, want %v", got, re)
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	r := Calibrate(20 * time.Millisecond)
+	if r <= 0 {
+		t.Fatalf("Calibrate() = %v, want a positive rate", r)
+	}
+	if got := Throughput(); got != r {
+		t.Errorf("Throughput() after Calibrate = %v, want %v", got, r)
+	}
+	if r := Calibrate(0); r <= 0 {
+		t.Errorf("Calibrate(0) = %v, want a positive rate", r)
+	}
+}
+
+func TestEstimateSolveTime(t *testing.T) {
+	storeThroughput(1000)
+	defer RecalibrateThroughput()
+	if got, want := EstimateSolveTime(2500), 2500*time.Millisecond; got != want {
+		t.Errorf("EstimateSolveTime(2500) = %v, want %v", got, want)
+	}
+	if got := EstimateSolveTime(0); got != 0 {
+		t.Errorf("EstimateSolveTime(0) = %v, want 0", got)
+	}
+	storeThroughput(0)
+	if got := EstimateSolveTime(2500); got != 0 {
+		t.Errorf("EstimateSolveTime(2500) with no throughput = %v, want 0", got)
+	}
+}
+
+func TestDifficultyForDuration(t *testing.T) {
+	storeThroughput(1000)
+	defer RecalibrateThroughput()
+	for _, tt := range []struct {
+		target time.Duration
+		want   uint32
+	}{
+		{10 * time.Second, 10000},
+		{2500 * time.Millisecond, 2500},
+		{0, 1},
+		{time.Microsecond, 1},
+		{1 << 62, math.MaxUint32},
+	} {
+		if got := DifficultyForDuration(tt.target); got != tt.want {
+			t.Errorf("DifficultyForDuration(%v) = %d, want %d", tt.target, got, tt.want)
+		}
+	}
+	if got := EstimateSolveTime(DifficultyForDuration(3 * time.Second)); got != 3*time.Second {
+		t.Errorf("round trip through EstimateSolveTime = %v, want 3s", got)
+	}
+}
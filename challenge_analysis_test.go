@@ -1,8 +1,6 @@
 package pow
 
 import (
-	"encoding/base64"
-	"encoding/binary"
 	"fmt"
 	"testing"
 	"time"
@@ -10,15 +8,15 @@ import (
 
 // analyzeChallengeString decodes and analyzes a challenge string
 func analyzeChallengeString(challengeStr string) (*Challenge, error) {
-	c, err := DecodeChallenge(challengeStr)
+	c, parts, err := DecodeChallengeDetailed(challengeStr)
 	if err != nil {
 		return nil, err
 	}
 	
 	fmt.Printf("Challenge Analysis:\n")
-	fmt.Printf("  Difficulty: %d\n", c.d)
+	fmt.Printf("  Difficulty: %d (%x)\n", c.d, parts.DifficultyBytes)
 	fmt.Printf("  Value: %s\n", c.x.String())
-	fmt.Printf("  Value (hex): %s\n", fmt.Sprintf("%x", c.x.Bytes()))
+	fmt.Printf("  Value (hex): %x\n", parts.ValueBytes)
 	
 	// Check if it's an edge case
 	if c.x.Sign() == 0 {
@@ -34,6 +32,9 @@ func analyzeChallengeString(challengeStr string) (*Challenge, error) {
 
 // TestSpecificChallengeAnalysis analyzes the specific challenge provided by the user
 func TestSpecificChallengeAnalysis(t *testing.T) {
+	if testing.Short() {
+		t.Skip("solves a difficulty-90000 challenge")
+	}
 	challengeStr := "s.AAFfkA==.wxZVoJ86n1h9CNavECXG4w=="
 	
 	fmt.Printf("\n=== ANALYZING SPECIFIC CHALLENGE ===\n")
@@ -94,6 +95,9 @@ func BenchmarkSpecificChallenge(b *testing.B) {
 
 // TestSpecificChallengePerformance measures actual performance for this specific challenge
 func TestSpecificChallengePerformance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("solves a difficulty-90000 challenge")
+	}
 	challengeStr := "s.AAFfkA==.wxZVoJ86n1h9CNavECXG4w=="
 	c, err := DecodeChallenge(challengeStr)
 	if err != nil {
@@ -154,23 +158,12 @@ func TestSpecificChallengePerformance(t *testing.T) {
 
 // TestDifficultyLevelAnalysis analyzes what difficulty level the challenge represents
 func TestDifficultyLevelAnalysis(t *testing.T) {
-	// Manually decode the difficulty to understand it better
-	parts := []string{"s", "AAFfkA==", "wxZVoJ86n1h9CNavECXG4w=="}
-	
-	dBytes, err := base64.StdEncoding.DecodeString(parts[1])
+	difficulty, err := ParseDifficulty("s.AAFfkA==.wxZVoJ86n1h9CNavECXG4w==")
 	if err != nil {
 		t.Fatalf("Failed to decode difficulty: %v", err)
 	}
 	
-	// Pad to 4 bytes if needed
-	if len(dBytes) < 4 {
-		dBytes = append(make([]byte, 4-len(dBytes)), dBytes...)
-	}
-	
-	difficulty := binary.BigEndian.Uint32(dBytes)
-	
 	fmt.Printf("\n=== DIFFICULTY ANALYSIS ===\n")
-	fmt.Printf("Difficulty bytes: %v\n", dBytes)
 	fmt.Printf("Difficulty value: %d\n", difficulty)
 	
 	// Categorize the difficulty
@@ -0,0 +1,73 @@
+package pow
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/redpwn/pow/powverify"
+)
+
+// TestCheckBigMatchesCheck verifies that the math/big verifier agrees with
+// Check on valid, alternate-root, and wrong solutions.
+func TestCheckBigMatchesCheck(t *testing.T) {
+	values := []*Int{
+		NewInt(0),
+		NewInt(1),
+		NewInt(2),
+		NewInt(12345),
+		NewInt(0).Sub(mod, one),
+		GenerateChallenge(1).x,
+	}
+	for _, d := range []uint32{0, 1, 2, 5, 17, 100} {
+		for _, x := range values {
+			c := &Challenge{d: d, x: x}
+			good := c.Solve()
+			y, err := ParamsP1279.decodeSolution(good)
+			if err != nil {
+				t.Fatalf("ParamsP1279.decodeSolution(%s): %v", good, err)
+			}
+			solutions := []string{
+				good,
+				ParamsP1279.encodeSolution(NewInt(0).Sub(mod, y)),
+				ParamsP1279.encodeSolution(NewInt(0).Add(y, one)),
+				ParamsP1279.encodeSolution(NewInt(0).Add(y, mod)),
+				"s.",
+				"x.AA==",
+			}
+			for _, s := range solutions {
+				t.Run(fmt.Sprintf("d%d/%s/%s", d, c, s), func(t *testing.T) {
+					want, wantErr := c.Check(s)
+					got, gotErr := powverify.CheckBig(c.String(), s)
+					if got != want || (gotErr == nil) != (wantErr == nil) {
+						t.Errorf("CheckBig = %v, %v; Check = %v, %v", got, gotErr, want, wantErr)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestPowverifyDecodeChallenge(t *testing.T) {
+	c := GenerateChallenge(7)
+	v, err := powverify.DecodeChallenge(c.StringURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Difficulty() != 7 {
+		t.Errorf("Difficulty = %d, want 7", v.Difficulty())
+	}
+	if ok, err := v.Check(c.Solve()); !ok || err != nil {
+		t.Errorf("Check = %v, %v; want true, nil", ok, err)
+	}
+
+	tooLarge := "s." + base64.StdEncoding.EncodeToString(EncodeDifficulty(1)) + "." + base64.StdEncoding.EncodeToString(mod.Bytes())
+	if _, err := powverify.DecodeChallenge(tooLarge); !errors.Is(err, powverify.ErrValueTooLarge) {
+		t.Errorf("DecodeChallenge of value out of range error = %v, want ErrValueTooLarge", err)
+	}
+	hard := &Challenge{d: powverify.MaxCheckDifficulty + 1, x: NewInt(5)}
+	if _, err := powverify.DecodeChallenge(hard.String()); !errors.Is(err, powverify.ErrDifficultyTooHigh) {
+		t.Errorf("DecodeChallenge of hard challenge error = %v, want ErrDifficultyTooHigh", err)
+	}
+}
@@ -0,0 +1,238 @@
+package pow
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+
+	"github.com/redpwn/pow/internal/wire"
+)
+
+// A CheckpointProof is a solution together with the values reached every
+// interval iterations along the way. Each stretch between consecutive
+// checkpoints, a segment, can be verified on its own, so SpotCheck can verify
+// a random sample of segments instead of the whole chain, and verify them in
+// parallel.
+//
+// Sampling trades assurance for cost. Undoing iterations is cheap, so a
+// prover that skipped the work can pick any solution and compute every
+// checkpoint backwards from it, leaving a single segment, the one joining the
+// chain to the challenge, that does not verify. Checking a fraction of the
+// segments therefore detects such a proof with about that probability, at
+// about that fraction of the cost of Check.
+type CheckpointProof struct {
+	interval uint32
+	values   []*Int // after interval, 2*interval, ..., and d iterations
+	p        *Params
+}
+
+// MaxCheckpoints is the largest number of checkpoints DecodeCheckpointProof
+// accepts.
+var MaxCheckpoints = 10000
+
+// ErrBadProof is returned by SpotCheck for checkpoint proofs whose
+// parameters or number of checkpoints do not fit the challenge.
+var ErrBadProof = errors.New("checkpoint proof does not fit challenge")
+
+// ErrBadInterval is returned by SolveCheckpointed for a zero interval.
+var ErrBadInterval = errors.New("checkpoint interval must be positive")
+
+// SolveCheckpointed is like SolveContext but records a checkpoint every
+// interval iterations, and after the last one. A challenge of difficulty 0
+// has a single checkpoint, its own value.
+func (c *Challenge) SolveCheckpointed(ctx context.Context, interval uint32) (*CheckpointProof, error) {
+	if interval == 0 {
+		return nil, ErrBadInterval
+	}
+	p := c.params()
+	pr := &CheckpointProof{interval: interval, p: p}
+	x := NewInt(0).Set(c.value())
+	done := ctx.Done()
+	for i := uint32(0); ; {
+		end := c.d
+		if c.d-i > interval {
+			end = i + interval
+		}
+		for i < end {
+			select {
+			case <-done:
+				return nil, ctx.Err()
+			default:
+			}
+			n := end - i
+			if n > solveBlock {
+				n = solveBlock
+			}
+			p.iterate(x, n)
+			i += n
+		}
+		pr.values = append(pr.values, NewInt(0).Set(x))
+		if i == c.d {
+			return pr, nil
+		}
+	}
+}
+
+// DecodeCheckpointProof decodes a proof produced by CheckpointProof.String.
+func DecodeCheckpointProof(s string) (*CheckpointProof, error) {
+	p, err := lookupPrefixedParams(s, wire.CheckpointPrefix)
+	if err != nil {
+		return nil, err
+	}
+	interval, values, err := p.format().ParseCheckpoints(s, MaxCheckpoints)
+	if err != nil {
+		return nil, err
+	}
+	pr := &CheckpointProof{interval: interval, values: make([]*Int, len(values)), p: p}
+	for i, v := range values {
+		pr.values[i] = NewInt(0).SetBytes(v)
+		if pr.values[i].Cmp(p.Modulus) >= 0 {
+			return nil, ErrValueTooLarge
+		}
+	}
+	return pr, nil
+}
+
+// String encodes the proof behind the prefix "k" followed by the version.
+func (pr *CheckpointProof) String() string {
+	values := make([][]byte, len(pr.values))
+	for i, v := range pr.values {
+		values[i] = v.Bytes()
+	}
+	return pr.p.format().EncodeCheckpoints(pr.interval, values)
+}
+
+// Solution returns the solution the proof ends in, as Solve would return it.
+func (pr *CheckpointProof) Solution() string {
+	return pr.p.encodeSolution(pr.values[len(pr.values)-1])
+}
+
+// Interval returns the number of iterations between checkpoints.
+func (pr *CheckpointProof) Interval() uint32 {
+	return pr.interval
+}
+
+// Segments returns the number of segments in the proof.
+func (pr *CheckpointProof) Segments() int {
+	return len(pr.values)
+}
+
+// SpotCheck verifies up to samples segments of proof, chosen at random, on
+// all CPUs; see CheckpointProof for what a sample guarantees. With samples
+// at least proof.Segments() every segment is checked, which is as strict as
+// Check on the proof's solution. It returns ErrBadProof if the proof was not
+// made for a challenge with c's parameters and difficulty.
+func (c *Challenge) SpotCheck(proof *CheckpointProof, samples int) (bool, error) {
+	if err := c.fitsProof(proof); err != nil {
+		return false, err
+	}
+	return c.checkSegments(context.Background(), proof, sampleSegments(len(proof.values), samples), 0)
+}
+
+// CheckCheckpointed verifies every segment of proof on workers goroutines,
+// or GOMAXPROCS of them if workers is not positive. Segments are independent,
+// so a proof with at least as many segments as workers is verified in about
+// 1/workers of the time Check takes; SolveCheckpointed with an interval of
+// d/GOMAXPROCS or less produces one. It stops at the first bad segment, and
+// returns ctx.Err() if ctx is done first, or ErrBadProof like SpotCheck.
+func (c *Challenge) CheckCheckpointed(ctx context.Context, proof *CheckpointProof, workers int) (bool, error) {
+	if err := c.fitsProof(proof); err != nil {
+		return false, err
+	}
+	return c.checkSegments(ctx, proof, sampleSegments(len(proof.values), len(proof.values)), workers)
+}
+
+// fitsProof returns the error for checking proof against c, if there is one.
+func (c *Challenge) fitsProof(proof *CheckpointProof) error {
+	if c.d > MaxCheckDifficulty {
+		return ErrDifficultyTooHigh
+	}
+	if RejectWeakChallenges && c.IsWeak() {
+		return ErrWeakChallenge
+	}
+	if proof.p.Version != c.params().Version || len(proof.values) != checkpointCount(c.d, proof.interval) {
+		return ErrBadProof
+	}
+	return nil
+}
+
+// checkSegments verifies the given segments of proof on a pool of workers,
+// as runPool counts them, stopping at the first bad one.
+func (c *Challenge) checkSegments(ctx context.Context, proof *CheckpointProof, segments []int, workers int) (bool, error) {
+	poolCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var bad int32
+	runPool(poolCtx, len(segments), workers, func(j int, y *Int) {
+		ok, err := c.checkSegment(poolCtx, proof, segments[j], y)
+		if err == nil && !ok {
+			atomic.StoreInt32(&bad, 1)
+			cancel()
+		}
+	})
+	if bad != 0 {
+		return false, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// checkSegment verifies segment i of proof, using y as scratch. It returns
+// ctx.Err() if ctx is done first.
+func (c *Challenge) checkSegment(ctx context.Context, proof *CheckpointProof, i int, y *Int) (bool, error) {
+	p := c.params()
+	start, n := c.value(), c.d
+	if i > 0 {
+		start = proof.values[i-1]
+		n -= uint32(i) * proof.interval
+	}
+	if n > proof.interval {
+		n = proof.interval
+	}
+	y.Set(proof.values[i])
+	if n == 0 {
+		return p.equalAny(y, start), nil
+	}
+	if err := p.unwindContext(ctx, y, n); err != nil {
+		return false, err
+	}
+	return p.equalUpToSign(y, start), nil
+}
+
+// checkpointCount returns the number of checkpoints SolveCheckpointed records
+// for difficulty d.
+func checkpointCount(d, interval uint32) int {
+	if d == 0 {
+		return 1
+	}
+	return int((uint64(d) + uint64(interval) - 1) / uint64(interval))
+}
+
+// sampleSegments returns min(samples, n) distinct indexes below n, chosen
+// uniformly at random, or at least one if samples is not positive.
+func sampleSegments(n, samples int) []int {
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	if samples >= n {
+		return idx
+	}
+	if samples < 1 {
+		samples = 1
+	}
+	// Partial Fisher-Yates shuffle. The prover must not be able to predict
+	// the sample, so it is drawn from crypto/rand.
+	var b [8]byte
+	for i := 0; i < samples; i++ {
+		if _, err := rand.Read(b[:]); err != nil {
+			panic(err)
+		}
+		j := i + int(binary.BigEndian.Uint64(b[:])%uint64(n-i))
+		idx[i], idx[j] = idx[j], idx[i]
+	}
+	return idx[:samples]
+}
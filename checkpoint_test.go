@@ -0,0 +1,170 @@
+package pow
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSolveCheckpointed(t *testing.T) {
+	for _, tt := range []struct {
+		d, interval uint32
+		segments    int
+	}{
+		{10, 3, 4},
+		{9, 3, 3},
+		{5, 10, 1},
+		{0, 4, 1},
+	} {
+		c := GenerateChallenge(tt.d)
+		pr, err := c.SolveCheckpointed(context.Background(), tt.interval)
+		if err != nil {
+			t.Fatalf("SolveCheckpointed: %v", err)
+		}
+		if pr.Segments() != tt.segments {
+			t.Errorf("d=%d interval=%d: %d segments, want %d", tt.d, tt.interval, pr.Segments(), tt.segments)
+		}
+		if got, want := pr.Solution(), c.Solve(); got != want {
+			t.Errorf("Solution() = %s, want %s", got, want)
+		}
+		decoded, err := DecodeCheckpointProof(pr.String())
+		if err != nil {
+			t.Fatalf("DecodeCheckpointProof(%s): %v", pr, err)
+		}
+		if decoded.String() != pr.String() || decoded.Interval() != tt.interval {
+			t.Errorf("round trip = %s, want %s", decoded, pr)
+		}
+		if ok, err := c.SpotCheck(decoded, pr.Segments()); !ok || err != nil {
+			t.Errorf("SpotCheck of a good proof = %v, %v; want true, nil", ok, err)
+		}
+		if ok, err := c.SpotCheck(decoded, 1); !ok || err != nil {
+			t.Errorf("sampled SpotCheck of a good proof = %v, %v; want true, nil", ok, err)
+		}
+	}
+
+	if _, err := GenerateChallenge(3).SolveCheckpointed(context.Background(), 0); err != ErrBadInterval {
+		t.Errorf("zero interval error = %v, want ErrBadInterval", err)
+	}
+}
+
+func TestSpotCheckBadProof(t *testing.T) {
+	c := GenerateChallenge(12)
+	pr, err := c.SolveCheckpointed(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forge the proof backwards from a different solution: every segment
+	// but the first verifies
+	forged := &CheckpointProof{interval: 3, p: pr.p, values: make([]*Int, 4)}
+	y := NewInt(12345)
+	for i := len(forged.values) - 1; i >= 0; i-- {
+		forged.values[i] = NewInt(0).Set(y)
+		pr.p.unwind(y, 3)
+	}
+	if ok, _ := c.SpotCheck(forged, forged.Segments()); ok {
+		t.Error("SpotCheck accepted a forged proof checking every segment")
+	}
+	if ok, err := c.CheckCheckpointed(context.Background(), forged, 2); ok || err != nil {
+		t.Errorf("CheckCheckpointed of a forged proof = %v, %v; want false, nil", ok, err)
+	}
+	ok1, _ := c.checkSegment(context.Background(), forged, 1, y)
+	ok0, _ := c.checkSegment(context.Background(), forged, 0, y)
+	if !ok1 || ok0 {
+		t.Error("forged proof should fail only its first segment")
+	}
+
+	other, _ := c.SolveCheckpointed(context.Background(), 4)
+	if ok, err := c.SpotCheck(other, 1); !ok || err != nil {
+		t.Errorf("SpotCheck with another interval = %v, %v; want true, nil", ok, err)
+	}
+	if _, err := GenerateChallenge(20).SpotCheck(pr, 1); err != ErrBadProof {
+		t.Errorf("SpotCheck for another difficulty = %v, want ErrBadProof", err)
+	}
+	if _, err := ParamsP2203.GenerateChallenge(12).SpotCheck(pr, 1); err != ErrBadProof {
+		t.Errorf("SpotCheck for other params = %v, want ErrBadProof", err)
+	}
+}
+
+func TestCheckCheckpointed(t *testing.T) {
+	c := GenerateChallenge(40)
+	pr, err := c.SolveCheckpointed(context.Background(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{0, 1, 3, 100} {
+		if ok, err := c.CheckCheckpointed(context.Background(), pr, workers); !ok || err != nil {
+			t.Errorf("CheckCheckpointed with %d workers = %v, %v; want true, nil", workers, ok, err)
+		}
+	}
+
+	// Tampering with any checkpoint is caught
+	bad := &CheckpointProof{interval: pr.interval, p: pr.p, values: append([]*Int(nil), pr.values...)}
+	bad.values[3] = NewInt(0).Add(pr.values[3], one)
+	if ok, err := c.CheckCheckpointed(context.Background(), bad, 0); ok || err != nil {
+		t.Errorf("CheckCheckpointed of a tampered proof = %v, %v; want false, nil", ok, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if ok, err := c.CheckCheckpointed(ctx, pr, 0); ok || err != context.Canceled {
+		t.Errorf("cancelled CheckCheckpointed = %v, %v; want false, context.Canceled", ok, err)
+	}
+	if _, err := GenerateChallenge(20).CheckCheckpointed(context.Background(), pr, 0); err != ErrBadProof {
+		t.Errorf("CheckCheckpointed for another difficulty = %v, want ErrBadProof", err)
+	}
+}
+
+func BenchmarkCheckCheckpointed(b *testing.B) {
+	c := GenerateChallenge(4000)
+	pr, err := c.SolveCheckpointed(context.Background(), 4000/uint32(runtime.GOMAXPROCS(0)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.CheckCheckpointed(context.Background(), pr, 0)
+	}
+}
+
+func TestDecodeCheckpointProofErrors(t *testing.T) {
+	pr, _ := GenerateChallenge(4).SolveCheckpointed(context.Background(), 2)
+	s := pr.String()
+	for _, bad := range []string{
+		pr.Solution(),
+		"ks.AAAAAA==",
+		"ks.AAAAAA==." + strings.TrimPrefix(pr.Solution(), "s."),
+		"ks." + strings.Repeat("AA.", MaxCheckpoints+2),
+	} {
+		if _, err := DecodeCheckpointProof(bad); err == nil {
+			t.Errorf("DecodeCheckpointProof(%.40s) succeeded", bad)
+		}
+	}
+	if _, err := DecodeCheckpointProof(s); err != nil {
+		t.Errorf("DecodeCheckpointProof: %v", err)
+	}
+	mod := ParamsP1279.encodeSolution(ParamsP1279.Modulus)
+	if _, err := DecodeCheckpointProof("ks.AAAAAg==." + strings.TrimPrefix(mod, "s.")); !errors.Is(err, ErrValueOutOfRange) {
+		t.Errorf("value out of range error = %v, want ErrValueOutOfRange", err)
+	}
+}
+
+func TestSampleSegments(t *testing.T) {
+	for _, tt := range []struct{ n, samples, want int }{
+		{10, 3, 3}, {10, 10, 10}, {10, 20, 10}, {10, 0, 1}, {1, 1, 1},
+	} {
+		got := sampleSegments(tt.n, tt.samples)
+		if len(got) != tt.want {
+			t.Errorf("sampleSegments(%d, %d) has %d indexes, want %d", tt.n, tt.samples, len(got), tt.want)
+		}
+		seen := make(map[int]bool)
+		for _, i := range got {
+			if i < 0 || i >= tt.n || seen[i] {
+				t.Errorf("sampleSegments(%d, %d) = %v, want distinct indexes below %d", tt.n, tt.samples, got, tt.n)
+			}
+			seen[i] = true
+		}
+	}
+}
@@ -0,0 +1,22 @@
+//go:build js && wasm
+
+// Command redpwnpowjs exposes the redpwnpow solver to browser JavaScript as
+// the global object redpwnpow. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o redpwnpow.wasm ./cmd/redpwnpowjs
+//
+// cgo is unavailable on js/wasm, so the package uses its math/big backend.
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/redpwn/pow"
+)
+
+func main() {
+	obj := js.Global().Get("Object").New()
+	pow.RegisterJS(obj)
+	js.Global().Set("redpwnpow", obj)
+	select {}
+}
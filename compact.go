@@ -0,0 +1,54 @@
+package pow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/redpwn/pow/internal/wire"
+)
+
+// CompactString encodes the challenge in a shorter form suited to QR codes
+// and links, which can be decoded by DecodeCompact. The difficulty and value
+// are packed into a single base62 number behind the prefix "c" followed by
+// the version, so "s.<d>.<x>" becomes "cs.<n>". It is never accepted by
+// DecodeChallenge, nor String's form by DecodeCompact.
+//
+// A base62 digit carries slightly less than a base64 one, so the saving comes
+// only from String's padding, separator and fixed-width difficulty. For
+// freshly generated challenges the compact form is about a fifth shorter than
+// String for difficulties below 16384, a sixth shorter up to 2^21, and a
+// tenth shorter at the largest difficulties; wider values save less still.
+func (c *Challenge) CompactString() string {
+	return c.params().format().EncodeCompact(c.d, c.value().Bytes())
+}
+
+// DecodeCompact decodes a challenge produced by CompactString.
+func DecodeCompact(v string) (*Challenge, error) {
+	p, err := lookupPrefixedParams(v, wire.CompactPrefix)
+	if err != nil {
+		return nil, err
+	}
+	d, xBytes, err := p.format().ParseCompact(v)
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.newChallenge(d, xBytes, MaxCheckDifficulty)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireCanonical(v, c.CompactString()); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// lookupPrefixedParams is like lookupParams for encodings whose version is
+// preceded by prefix.
+func lookupPrefixedParams(v, prefix string) (*Params, error) {
+	version := wire.Prefix(v)
+	p, ok := paramsForVersion(strings.TrimPrefix(version, prefix))
+	if !ok || !strings.HasPrefix(version, prefix) {
+		return nil, fmt.Errorf("%w %q", ErrBadVersion, version)
+	}
+	return p, nil
+}
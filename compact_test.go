@@ -0,0 +1,71 @@
+package pow
+
+import (
+	"testing"
+)
+
+func TestCompactRoundTrip(t *testing.T) {
+	defer func(old uint32) { MaxCheckDifficulty = old }(MaxCheckDifficulty)
+	MaxCheckDifficulty = 1<<32 - 1
+	challenges := []*Challenge{
+		{},
+		{d: 0, x: NewInt(0)},
+		{d: 1, x: NewInt(1)},
+		{d: 1<<32 - 1, x: NewInt(255)},
+		{d: 256, x: NewInt(256)},
+		{d: 1 << 24, x: NewInt(0)},
+		{d: 7, x: NewInt(0).Sub(mod, one)},
+		{d: 7, x: NewInt(0).Sub(ParamsP2203.Modulus, one), p: ParamsP2203},
+	}
+	for i := 0; i < 100; i++ {
+		challenges = append(challenges, GenerateChallenge(uint32(i)<<(i%32)))
+	}
+	for _, c := range challenges {
+		s := c.CompactString()
+		decoded, err := DecodeCompact(s)
+		if err != nil {
+			t.Fatalf("DecodeCompact(%s): %v", s, err)
+		}
+		if got, want := decoded.String(), c.String(); got != want {
+			t.Errorf("DecodeCompact(%s) = %s, want %s", s, got, want)
+		}
+		if _, err := DecodeChallenge(s); err == nil {
+			t.Errorf("DecodeChallenge accepted compact form %s", s)
+		}
+		if _, err := DecodeCompact(c.String()); err == nil {
+			t.Errorf("DecodeCompact accepted standard form %s", c)
+		}
+	}
+}
+
+func TestCompactShorter(t *testing.T) {
+	c := GenerateChallenge(90000)
+	c.x.SetBit(c.x, 127, 1)
+	if compact, std := len(c.CompactString()), len(c.String()); compact*6 > std*5 {
+		t.Errorf("compact form is %d bytes, standard form %d", compact, std)
+	}
+	c.d = 1337
+	if compact, std := len(c.CompactString()), len(c.String()); compact*5 > std*4 {
+		t.Errorf("compact form is %d bytes, standard form %d at difficulty 1337", compact, std)
+	}
+}
+
+func TestDecodeCompactRejects(t *testing.T) {
+	for _, v := range []string{
+		"",
+		"cs.",
+		"cs.-1",
+		"cs.+1",
+		"cs.a_b",
+		"cs.!",
+		"cx.1",
+		"s.1",
+		"cs.1.1",
+		"cs." + string(make([]byte, 400)),
+		"cs.24", // 0x80, a difficulty with no first byte
+	} {
+		if c, err := DecodeCompact(v); err == nil {
+			t.Errorf("DecodeCompact(%q) = %s, want error", v, c)
+		}
+	}
+}
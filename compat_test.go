@@ -0,0 +1,53 @@
+package pow
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestKCTFVectors checks interoperability with kCTF's reference pow.py, which
+// redpwnpow replaces. testdata/kctf_vectors.txt holds challenge/solution pairs
+// produced by testdata/kctf_vectors.py, a copy of the reference sloth and
+// number encoding code. kCTF pads numbers to a multiple of three bytes, so
+// solutions are compared by value rather than by string.
+func TestKCTFVectors(t *testing.T) {
+	f, err := os.Open("testdata/kctf_vectors.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 2 {
+			t.Fatalf("malformed vector line %q", sc.Text())
+		}
+		challenge, solution := fields[0], fields[1]
+		c, err := DecodeChallenge(challenge)
+		if err != nil {
+			t.Errorf("DecodeChallenge(%s): %v", challenge, err)
+			continue
+		}
+		if good, err := c.Check(solution); err != nil || !good {
+			t.Errorf("Check(%s, %s) = %v, %v; want true, nil", challenge, solution, good, err)
+		}
+		want, err := ParamsP1279.decodeSolution(solution)
+		if err != nil {
+			t.Errorf("ParamsP1279.decodeSolution(%s): %v", solution, err)
+			continue
+		}
+		got, err := ParamsP1279.decodeSolution(c.Solve())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Cmp(want) != 0 {
+			t.Errorf("Solve(%s) = %s, want %s", challenge, got, want)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,143 @@
+package pow
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// A challenge can be bound to opaque context data, such as a client address
+// or session identifier, by carrying that data in its value x. The big-endian
+// bytes of a bound x are laid out as
+//
+//	nonce (16 bytes) || extra || len(extra) (2 bytes, big-endian)
+//
+// The length is stored last because leading zero bytes of the nonce are lost
+// in the encoding; the value is left-padded back to the full layout length
+// before it is parsed. Signed challenges bound to a context insert
+// extra || len(extra) between the nonce and the expiry, so the MAC covers it.
+
+// MaxContextSize is the longest extra data that can be bound to a challenge.
+const MaxContextSize = 100
+
+// ErrContextTooLong is returned when binding more than MaxContextSize bytes
+// of extra data to a challenge.
+var ErrContextTooLong = errors.New("context data too long")
+
+// ErrContextMismatch is returned when a challenge is not bound to the
+// expected context data.
+var ErrContextMismatch = errors.New("challenge not bound to context")
+
+const nonceSize = 16
+
+// GenerateChallengeWithContext creates a new random challenge bound to extra.
+func GenerateChallengeWithContext(d uint32, extra []byte) (*Challenge, error) {
+	tail, err := contextTail(extra)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, nonceSize, nonceSize+len(tail))
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b = append(b, tail...)
+	return &Challenge{
+		x: NewInt(0).SetBytes(b),
+		d: d,
+	}, nil
+}
+
+// CheckBound is like Check but first confirms that the challenge is bound to
+// extra, returning ErrContextMismatch if it is not. Like
+// GenerateChallengeWithContext, it only deals in challenges over ParamsP1279.
+func (c *Challenge) CheckBound(s string, extra []byte) (bool, error) {
+	tail, err := contextTail(extra)
+	if err != nil {
+		return false, err
+	}
+	b, ok := padValue(c.value(), nonceSize+len(tail))
+	if !ok || !c.issuedParams() || !bytes.Equal(b[nonceSize:], tail) {
+		return false, ErrContextMismatch
+	}
+	return c.Check(s)
+}
+
+// contextTail returns the bytes that follow the nonce in the value of a
+// challenge bound to extra.
+func contextTail(extra []byte) ([]byte, error) {
+	if len(extra) > MaxContextSize {
+		return nil, ErrContextTooLong
+	}
+	tail := make([]byte, len(extra), len(extra)+2)
+	copy(tail, extra)
+	return append(tail, byte(len(extra)>>8), byte(len(extra))), nil
+}
+
+// padValue returns the big-endian bytes of x left-padded to n bytes. It
+// reports false if x does not fit in n bytes.
+func padValue(x *Int, n int) ([]byte, bool) {
+	xBytes := x.Bytes()
+	if len(xBytes) > n {
+		return nil, false
+	}
+	b := make([]byte, n)
+	copy(b[n-len(xBytes):], xBytes)
+	return b, true
+}
+
+// A challenge can also be bound to a client without revealing anything about
+// it, by deriving the value from a server secret. The big-endian bytes of x
+// are then
+//
+//	nonce (16 bytes) || HMAC-SHA256(secret, "client" || 0x00 || d || nonce || client)
+//
+// with d as four bytes big-endian. Only the holder of the secret can produce
+// or recompute the MAC, so a solution only validates for the client the
+// challenge was issued to.
+
+// GenerateClientChallenge creates a new random challenge bound to client
+// under secret. Unlike GenerateChallengeWithContext, client is not visible in
+// the challenge and may be of any length.
+func GenerateClientChallenge(secret, client []byte, d uint32) *Challenge {
+	b := make([]byte, nonceSize, nonceSize+sha256.Size)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b = append(b, clientMAC(secret, client, d, b)...)
+	return &Challenge{
+		x: NewInt(0).SetBytes(b),
+		d: d,
+	}
+}
+
+// CheckClient is like Check but first confirms that the challenge was issued
+// by GenerateClientChallenge to client under secret, returning
+// ErrContextMismatch if it was not.
+func (c *Challenge) CheckClient(secret, client []byte, s string) (bool, error) {
+	b, ok := padValue(c.value(), nonceSize+sha256.Size)
+	if !ok || !c.issuedParams() || !hmac.Equal(b[nonceSize:], clientMAC(secret, client, c.d, b[:nonceSize])) {
+		return false, ErrContextMismatch
+	}
+	return c.Check(s)
+}
+
+// issuedParams reports whether c is over ParamsP1279, the parameters bound
+// and client challenges are issued with. The binding does not cover the
+// version, so a challenge moved to a cheaper registered field must not pass.
+func (c *Challenge) issuedParams() bool {
+	return c.params() == ParamsP1279
+}
+
+func clientMAC(secret, client []byte, d uint32, nonce []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	var dBytes [4]byte
+	binary.BigEndian.PutUint32(dBytes[:], d)
+	h.Write([]byte("client\x00"))
+	h.Write(dBytes[:])
+	h.Write(nonce)
+	h.Write(client)
+	return h.Sum(nil)
+}
@@ -0,0 +1,117 @@
+package pow
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCheckBound(t *testing.T) {
+	extra := []byte("203.0.113.7 /login")
+	c, err := GenerateChallengeWithContext(5, extra)
+	if err != nil {
+		t.Fatalf("GenerateChallengeWithContext failed: %v", err)
+	}
+	decoded, err := DecodeChallenge(c.String())
+	if err != nil {
+		t.Fatalf("DecodeChallenge(%s): %v", c, err)
+	}
+	solution := c.Solve()
+
+	if good, err := decoded.CheckBound(solution, extra); err != nil || !good {
+		t.Errorf("CheckBound = %v, %v; want true, nil", good, err)
+	}
+	for _, other := range [][]byte{nil, []byte("203.0.113.8 /login"), extra[1:]} {
+		if _, err := decoded.CheckBound(solution, other); !errors.Is(err, ErrContextMismatch) {
+			t.Errorf("CheckBound with context %q error = %v, want ErrContextMismatch", other, err)
+		}
+	}
+	// the binding does not survive a move to another field
+	moved := &Challenge{d: c.d, x: c.x, p: ParamsP2203}
+	if _, err := moved.CheckBound(moved.Solve(), extra); !errors.Is(err, ErrContextMismatch) {
+		t.Errorf("CheckBound in another field error = %v, want ErrContextMismatch", err)
+	}
+	if _, err := GenerateChallengeWithContext(5, make([]byte, MaxContextSize+1)); !errors.Is(err, ErrContextTooLong) {
+		t.Errorf("GenerateChallengeWithContext with oversize context error = %v, want ErrContextTooLong", err)
+	}
+}
+
+func TestCheckBoundEmptyContext(t *testing.T) {
+	c, err := GenerateChallengeWithContext(1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if good, err := c.CheckBound(c.Solve(), nil); err != nil || !good {
+		t.Errorf("CheckBound = %v, %v; want true, nil", good, err)
+	}
+	// an unbound challenge is not bound to the empty context
+	c = GenerateChallenge(1)
+	if _, err := c.CheckBound(c.Solve(), nil); !errors.Is(err, ErrContextMismatch) {
+		t.Errorf("CheckBound on unbound challenge error = %v, want ErrContextMismatch", err)
+	}
+}
+
+func TestSignedChallengeWithContext(t *testing.T) {
+	key := []byte("secret")
+	extra := bytes.Repeat([]byte{0xaa}, MaxContextSize)
+	challenge, err := SignedChallengeWithContext(key, 3, extra)
+	if err != nil {
+		t.Fatalf("SignedChallengeWithContext failed: %v", err)
+	}
+	c, err := DecodeChallenge(challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution := c.Solve()
+
+	if good, err := VerifySignedSolutionWithContext(key, extra, challenge, solution); err != nil || !good {
+		t.Errorf("VerifySignedSolutionWithContext = %v, %v; want true, nil", good, err)
+	}
+	if _, err := VerifySignedSolutionWithContext(key, extra[1:], challenge, solution); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("VerifySignedSolutionWithContext with other context error = %v, want ErrBadSignature", err)
+	}
+	if _, err := VerifySignedSolution(key, challenge, solution); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("VerifySignedSolution on bound challenge error = %v, want ErrBadSignature", err)
+	}
+}
+
+func TestCheckClient(t *testing.T) {
+	secret := []byte("server secret")
+	client := []byte("203.0.113.7")
+	c := GenerateClientChallenge(secret, client, 5)
+	decoded, err := DecodeChallenge(c.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(c.ValueBytes(), client) {
+		t.Error("client identifier is visible in the challenge")
+	}
+	solution := c.Solve()
+	if good, err := decoded.CheckClient(secret, client, solution); !good || err != nil {
+		t.Errorf("CheckClient = %v, %v; want true, nil", good, err)
+	}
+	for _, tc := range []struct {
+		secret, client []byte
+	}{
+		{secret, []byte("203.0.113.8")},
+		{[]byte("other secret"), client},
+		{secret, nil},
+	} {
+		if _, err := decoded.CheckClient(tc.secret, tc.client, solution); !errors.Is(err, ErrContextMismatch) {
+			t.Errorf("CheckClient(%q, %q): err = %v, want ErrContextMismatch", tc.secret, tc.client, err)
+		}
+	}
+	// the difficulty is covered by the MAC too
+	other := &Challenge{d: c.d + 1, x: c.x}
+	if _, err := other.CheckClient(secret, client, other.Solve()); !errors.Is(err, ErrContextMismatch) {
+		t.Errorf("CheckClient with a changed difficulty: err = %v, want ErrContextMismatch", err)
+	}
+	// and so is the field
+	moved := &Challenge{d: c.d, x: c.x, p: ParamsP2203}
+	if _, err := moved.CheckClient(secret, client, moved.Solve()); !errors.Is(err, ErrContextMismatch) {
+		t.Errorf("CheckClient in another field: err = %v, want ErrContextMismatch", err)
+	}
+	if _, err := GenerateChallenge(5).CheckClient(secret, client, solution); !errors.Is(err, ErrContextMismatch) {
+		t.Errorf("CheckClient on an unbound challenge: err = %v, want ErrContextMismatch", err)
+	}
+}
@@ -0,0 +1,125 @@
+package pow
+
+import (
+	"sync"
+	"time"
+)
+
+// A DifficultyController picks the difficulty to issue from the load on the
+// server, doubling it under load up to a maximum and halving it back down to
+// a minimum as load subsides, so that proof of work gets more expensive
+// during an attack and stays cheap otherwise. It is safe for concurrent use.
+//
+// Load is sampled lazily, from calls to Difficulty, once per Interval. Each
+// sample is the largest of the load signals, where 1 means at capacity, and
+// is smoothed with an exponential moving average. The difficulty only rises
+// above High and only falls below Low, and changes at most once per sample,
+// so it does not oscillate when load hovers near a threshold.
+type DifficultyController struct {
+	// Interval is how often load is sampled and the difficulty adjusted.
+	// The default is 10 seconds.
+	Interval time.Duration
+	// TargetRate, if positive, adds the rate of calls to Difficulty as a
+	// load signal, reading 1 at TargetRate calls per second. As each
+	// issued challenge calls Difficulty once, this is the request rate.
+	TargetRate float64
+	// Smoothing is the weight of each sample in the moving average, in
+	// (0, 1]. The default is 0.3; 1 disables smoothing.
+	Smoothing float64
+	// High and Low are the smoothed loads above which the difficulty
+	// doubles and below which it halves. If both are zero they default to
+	// 0.8 and 0.4.
+	High, Low float64
+
+	min, max uint32
+	signals  []func() float64
+
+	mu       sync.Mutex
+	d        uint32
+	load     float64
+	requests uint64
+	last     time.Time
+}
+
+// NewDifficultyController returns a DifficultyController issuing
+// difficulties between min and max, starting at min, and reading load from
+// signals. Signals are called with the controller locked, so they must be
+// quick; Verifier.Load is one.
+func NewDifficultyController(min, max uint32, signals ...func() float64) *DifficultyController {
+	if max < min {
+		max = min
+	}
+	return &DifficultyController{min: min, max: max, signals: signals, d: min}
+}
+
+// Difficulty returns the difficulty to issue now, adjusting it first if an
+// Interval has passed since the last adjustment. It suits
+// Server.Difficulty.
+func (dc *DifficultyController) Difficulty() uint32 {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.requests++
+	t := now()
+	if dc.last.IsZero() {
+		dc.last = t
+	}
+	interval := dc.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	if elapsed := t.Sub(dc.last); elapsed >= interval {
+		dc.adjust(dc.sample(elapsed))
+		dc.requests = 0
+		dc.last = t
+	}
+	return dc.d
+}
+
+// Load returns the smoothed load as of the last adjustment.
+func (dc *DifficultyController) Load() float64 {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return dc.load
+}
+
+// sample returns the largest of the load signals over the last elapsed.
+func (dc *DifficultyController) sample(elapsed time.Duration) float64 {
+	var load float64
+	if dc.TargetRate > 0 {
+		load = float64(dc.requests) / elapsed.Seconds() / dc.TargetRate
+	}
+	for _, f := range dc.signals {
+		if l := f(); l > load {
+			load = l
+		}
+	}
+	return load
+}
+
+func (dc *DifficultyController) adjust(sample float64) {
+	alpha := dc.Smoothing
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.3
+	}
+	high, low := dc.High, dc.Low
+	if high == 0 && low == 0 {
+		high, low = 0.8, 0.4
+	}
+	dc.load = alpha*sample + (1-alpha)*dc.load
+	switch {
+	case dc.load > high && dc.d < dc.max:
+		d := 2 * uint64(dc.d)
+		if d == 0 {
+			d = 1
+		}
+		if d > uint64(dc.max) {
+			d = uint64(dc.max)
+		}
+		dc.d = uint32(d)
+	case dc.load < low && dc.d > dc.min:
+		dc.d /= 2
+		if dc.d < dc.min {
+			dc.d = dc.min
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package pow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDifficultyController(t *testing.T) {
+	defer func() { now = time.Now }()
+	clock := time.Unix(1700000000, 0)
+	now = func() time.Time { return clock }
+
+	load := 0.0
+	dc := NewDifficultyController(1000, 5000, func() float64 { return load })
+	dc.Interval = time.Second
+	dc.Smoothing = 1
+	step := func() uint32 {
+		clock = clock.Add(time.Second)
+		return dc.Difficulty()
+	}
+
+	if d := dc.Difficulty(); d != 1000 {
+		t.Fatalf("initial Difficulty = %d, want 1000", d)
+	}
+	// Under load the difficulty doubles once per interval, up to the max
+	load = 2
+	for _, want := range []uint32{2000, 4000, 5000, 5000} {
+		if d := step(); d != want {
+			t.Errorf("Difficulty under load = %d, want %d", d, want)
+		}
+	}
+	// Between the thresholds it holds
+	load = 0.6
+	if d := step(); d != 5000 {
+		t.Errorf("Difficulty at moderate load = %d, want 5000", d)
+	}
+	// Calls within an interval do not adjust it
+	load = 0
+	if d := dc.Difficulty(); d != 5000 {
+		t.Errorf("Difficulty before the interval passed = %d, want 5000", d)
+	}
+	for _, want := range []uint32{2500, 1250, 1000} {
+		if d := step(); d != want {
+			t.Errorf("Difficulty when idle = %d, want %d", d, want)
+		}
+	}
+}
+
+func TestDifficultyControllerSmoothing(t *testing.T) {
+	defer func() { now = time.Now }()
+	clock := time.Unix(1700000000, 0)
+	now = func() time.Time { return clock }
+
+	load := 0.0
+	dc := NewDifficultyController(10, 1000, func() float64 { return load })
+	dc.Interval = time.Second
+	dc.Difficulty()
+	// A single spike is smoothed away
+	load = 2
+	clock = clock.Add(time.Second)
+	if d := dc.Difficulty(); d != 10 {
+		t.Errorf("Difficulty after a spike = %d, want 10", d)
+	}
+	if l := dc.Load(); l < 0.5 || l > 0.7 {
+		t.Errorf("Load = %v, want 0.6", l)
+	}
+	// A sustained one is not
+	clock = clock.Add(time.Second)
+	if d := dc.Difficulty(); d != 20 {
+		t.Errorf("Difficulty after sustained load = %d, want 20", d)
+	}
+}
+
+func TestDifficultyControllerRate(t *testing.T) {
+	defer func() { now = time.Now }()
+	clock := time.Unix(1700000000, 0)
+	now = func() time.Time { return clock }
+
+	dc := NewDifficultyController(100, 800)
+	dc.Interval = time.Second
+	dc.TargetRate = 10
+	dc.Smoothing = 1
+	dc.Difficulty()
+	for i := 0; i < 50; i++ {
+		dc.Difficulty()
+	}
+	clock = clock.Add(time.Second)
+	if d := dc.Difficulty(); d != 200 {
+		t.Errorf("Difficulty at 5x the target rate = %d, want 200", d)
+	}
+
+	// Servers issue at the controlled difficulty
+	s := NewServer(1, time.Minute, nil)
+	defer s.Close()
+	s.Difficulty = dc.Difficulty
+	challenge, err := s.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := ParseDifficulty(challenge); d != 200 {
+		t.Errorf("issued difficulty = %d, want 200", d)
+	}
+}
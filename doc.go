@@ -0,0 +1,47 @@
+// Package pow implements redpwnpow, a non-parallelizable proof of work that is
+// compatible with kCTF.
+//
+// A challenge consists of a difficulty d and a value x modulo a Mersenne
+// prime p, 2^1279-1 by default. Solving applies x -> x^((p+1)/4) XOR 1 mod p
+// d times. Each step is a modular square root, which costs about log2(p)
+// squarings, and must wait for the previous one. Check undoes each step with
+// a single squaring, so verifying is roughly log2(p) times cheaper than
+// solving.
+//
+// Verification is linear in d. Wesolowski and Pietrzak proofs, which would
+// make it logarithmic or constant, certify that y = x^(2^d) in a group of
+// unknown order, and neither condition holds here. The order p-1 of the field
+// is public, so anyone can compute x^(2^d) directly with a single
+// exponentiation by 2^d mod p-1, and the XOR between square roots breaks the
+// algebraic relation between x and the solution that such proofs rely on.
+// Succinct verification would need a different scheme over an RSA or class
+// group, which this package does not provide, so there is no Wesolowski
+// version of the wire format. CheckpointProof and SpotCheck cut the cost of
+// verification instead, at the price of a probabilistic guarantee. The same
+// goes for Pietrzak's halving proofs. A deployment that wants either over a
+// suitable group can plug its own puzzle in with RegisterScheme.
+//
+// There is no GPU backend, because a GPU cannot speed up a solve. An
+// iteration is a chain of 1277 squarings of a 1279-bit number, each needing
+// the result of the one before, and a single squaring is far too small to
+// spread across the threads of a GPU. Each squaring would run slower there
+// than on one CPU core, before counting the transfers. A GPU could only help
+// by solving many challenges side by side, and a solver farm gets that more
+// cheaply by running one solve per CPU core, as SolveAll does.
+//
+// Solving and checking use a fixed amount of memory that does not depend on
+// the difficulty. The 2^1279-1 kernels keep their limbs in fixed-size arrays
+// on the stack, and the Ints of Solve and Check, a few hundred bytes each for
+// the built-in fields, come from a pool that grows only with the number of
+// concurrent calls. Decoding rejects values longer than the modulus before
+// allocating them. The only inputs whose size the caller must bound are
+// checkpoint proofs, limited by MaxCheckpoints, and the batches passed to
+// SolveAll. There is therefore no separate limit on scratch memory.
+//
+// Difficulties are 32-bit, both on the wire and in the API: GenerateChallenge,
+// ForEach, SolveStats and MaxCheckDifficulty all use uint32. A wider
+// difficulty would need a new wire version and a break in every one of those
+// signatures, and would buy little, since checking 2^32 iterations already
+// takes hours. Longer delays can instead be built by chaining challenges,
+// deriving each one from the previous solution with ChallengeFromSeed.
+package pow
@@ -0,0 +1,59 @@
+package pow
+
+import (
+	"github.com/redpwn/pow/internal/wire"
+)
+
+// EncodeHex encodes the challenge with its difficulty and value in hex
+// instead of base64, for environments that only allow hex-friendly copy and
+// paste. The version is prefixed with "h", as in "hs.<d>.<x>", so that the
+// result is never mistaken for the standard form. It can be decoded by
+// DecodeChallengeHex.
+func (c *Challenge) EncodeHex() string {
+	return c.params().format().EncodeHex(c.d, c.value().Bytes())
+}
+
+// DecodeChallengeHex decodes a challenge produced by EncodeHex. It applies the
+// same limits as DecodeChallenge.
+func DecodeChallengeHex(v string) (*Challenge, error) {
+	p, err := lookupPrefixedParams(v, wire.HexPrefix)
+	if err != nil {
+		return nil, err
+	}
+	d, xBytes, err := p.format().ParseChallengeHex(v)
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.newChallenge(d, xBytes, MaxCheckDifficulty)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireCanonical(v, c.EncodeHex()); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// EncodeHex encodes the solution in hex, as in "hs.<y>". It can be decoded by
+// DecodeSolutionHex.
+func (s *Solution) EncodeHex() string {
+	return s.params().format().EncodeSolutionHex(s.value().Bytes())
+}
+
+// DecodeSolutionHex decodes a solution produced by Solution.EncodeHex. Its
+// String method gives the form Check expects.
+func DecodeSolutionHex(v string) (*Solution, error) {
+	p, err := lookupPrefixedParams(v, wire.HexPrefix)
+	if err != nil {
+		return nil, err
+	}
+	yBytes, err := p.format().ParseSolutionHex(v)
+	if err != nil {
+		return nil, err
+	}
+	y := NewInt(0).SetBytes(yBytes)
+	if y.Cmp(p.Modulus) >= 0 {
+		return nil, ErrValueTooLarge
+	}
+	return &Solution{y: y, p: p}, nil
+}
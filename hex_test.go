@@ -0,0 +1,68 @@
+package pow
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHexRoundTrip(t *testing.T) {
+	c := &Challenge{d: 90000, x: NewInt(0xc316)}
+	if got, want := c.EncodeHex(), "hs.00015f90.c316"; got != want {
+		t.Errorf("EncodeHex() = %s, want %s", got, want)
+	}
+	for _, c := range []*Challenge{
+		c,
+		{},
+		{d: 7, x: NewInt(0).Sub(ParamsP2203.Modulus, one), p: ParamsP2203},
+		GenerateChallenge(3),
+	} {
+		h := c.EncodeHex()
+		got, err := DecodeChallengeHex(h)
+		if err != nil {
+			t.Fatalf("DecodeChallengeHex(%s): %v", h, err)
+		}
+		if got.String() != c.String() {
+			t.Errorf("DecodeChallengeHex(%s) = %s, want %s", h, got, c)
+		}
+		if _, err := DecodeChallenge(h); err == nil {
+			t.Errorf("DecodeChallenge accepted hex form %s", h)
+		}
+	}
+
+	c = GenerateChallenge(3)
+	sol, err := DecodeSolution(c.Solve())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs, err := DecodeSolutionHex(sol.EncodeHex())
+	if err != nil {
+		t.Fatalf("DecodeSolutionHex(%s): %v", sol.EncodeHex(), err)
+	}
+	if ok, err := c.Check(hs.String()); !ok || err != nil {
+		t.Errorf("Check after hex round trip = %v, %v", ok, err)
+	}
+}
+
+func TestDecodeHexErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want error
+	}{
+		{"s.00000001.02", ErrBadVersion},
+		{"hx.00000001.02", ErrBadVersion},
+		{"hs.00000001", ErrBadEncoding},
+		{"hs.00000001.0g", ErrBadEncoding},
+		{"hs.00000001.123", ErrBadEncoding},
+		{"hs.0100000000.02", ErrBadDifficulty},
+		{"hs.00000001." + strings.Repeat("ff", 161), ErrValueOutOfRange},
+	}
+	for _, tt := range tests {
+		if _, err := DecodeChallengeHex(tt.in); !errors.Is(err, tt.want) {
+			t.Errorf("DecodeChallengeHex(%q): err = %v, want %v", tt.in, err, tt.want)
+		}
+	}
+	if _, err := DecodeSolutionHex("hs." + strings.Repeat("ff", 160)); !errors.Is(err, ErrValueOutOfRange) {
+		t.Errorf("DecodeSolutionHex above the modulus: err = %v, want %v", err, ErrValueOutOfRange)
+	}
+}
@@ -0,0 +1,67 @@
+package wire
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// CheckpointPrefix is prepended to Version to form the prefix of checkpoint
+// proofs, which list the interval and then every checkpoint value:
+//
+//	"k" || Version || "." || interval || "." || value || "." || value ...
+//
+// with the interval and values in base64 like the standard form.
+const CheckpointPrefix = "k"
+
+var (
+	errCheckpointCount = fmt.Errorf("%w: too many checkpoints", ErrBadEncoding)
+	errInterval        = fmt.Errorf("%w: zero checkpoint interval", ErrBadEncoding)
+)
+
+// EncodeCheckpoints encodes a checkpoint proof with the given interval and
+// the big-endian bytes of its values.
+func (f Format) EncodeCheckpoints(interval uint32, values [][]byte) string {
+	var b strings.Builder
+	b.WriteString(CheckpointPrefix + f.Version + ".")
+	b.WriteString(base64.StdEncoding.EncodeToString(EncodeDifficulty(interval)))
+	for _, v := range values {
+		b.WriteByte('.')
+		b.WriteString(base64.StdEncoding.EncodeToString(v))
+	}
+	return b.String()
+}
+
+// ParseCheckpoints decodes a checkpoint proof into its interval and the
+// big-endian bytes of its values. Proofs with more than max values are
+// rejected before any of them are decoded.
+func (f Format) ParseCheckpoints(s string, max int) (uint32, [][]byte, error) {
+	s = strings.TrimSpace(s)
+	n := strings.Count(s, ".") + 1
+	if n > max+2 {
+		return 0, nil, errCheckpointCount
+	}
+	if n < 3 {
+		n = 3 // so that split reports a short proof as malformed
+	}
+	g := f
+	g.Version = CheckpointPrefix + f.Version
+	parts, err := g.split(s, n)
+	if err != nil {
+		return 0, nil, err
+	}
+	interval, err := f.decodeDifficulty(parts[1])
+	if err != nil {
+		return 0, nil, err
+	}
+	if interval == 0 {
+		return 0, nil, errInterval
+	}
+	values := make([][]byte, len(parts)-2)
+	for i, v := range parts[2:] {
+		if values[i], err = f.decodeValue(v); err != nil {
+			return 0, nil, err
+		}
+	}
+	return interval, values, nil
+}
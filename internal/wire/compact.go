@@ -0,0 +1,84 @@
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// CompactPrefix is prepended to Version to form the prefix of the compact
+// challenge encoding, so that compact and standard challenges can never be
+// mistaken for each other.
+const CompactPrefix = "c"
+
+var errCompact = fmt.Errorf("%w: malformed compact challenge", ErrBadEncoding)
+
+// The compact form packs a challenge into one base62 number whose big-endian
+// bytes are the minimal bytes of the value followed by the difficulty in
+// base 128, most significant digit first. Every difficulty byte except the
+// first has its high bit set, so the difficulty is delimited by reading
+// backwards from the end up to and including the first byte with the high
+// bit clear. Leading zero bytes can then only belong to the value and are
+// dropped harmlessly when the bytes are read as a number.
+
+// EncodeCompact encodes a challenge of difficulty d and value bytes x in the
+// compact form.
+func (f Format) EncodeCompact(d uint32, x []byte) string {
+	var db [5]byte
+	i := len(db) - 1
+	db[i] = byte(d & 0x7f)
+	for d >>= 7; d > 0; d >>= 7 {
+		db[i] |= 0x80
+		i--
+		db[i] = byte(d & 0x7f)
+	}
+	x = bytes.TrimLeft(x, "\x00")
+	b := make([]byte, 0, len(x)+len(db)-i)
+	b = append(append(b, x...), db[i:]...)
+	return CompactPrefix + f.Version + "." + new(big.Int).SetBytes(b).Text(62)
+}
+
+// ParseCompact decodes a challenge in the compact form into its difficulty
+// and the big-endian bytes of its starting value.
+func (f Format) ParseCompact(v string) (uint32, []byte, error) {
+	v = strings.TrimSpace(v)
+	prefix := CompactPrefix + f.Version + "."
+	if !strings.HasPrefix(v, prefix) {
+		return 0, nil, fmt.Errorf("%w %q", ErrBadVersion, Prefix(v))
+	}
+	v = v[len(prefix):]
+	// log2(62) > 5.9, so each byte takes fewer than two digits
+	if len(v) == 0 || len(v) > 2*(f.MaxValueBytes+5) {
+		return 0, nil, errCompact
+	}
+	// SetString would accept a sign, which is not a base62 digit
+	n, ok := new(big.Int).SetString(v, 62)
+	if !ok || strings.IndexAny(v, "+-") >= 0 {
+		return 0, nil, errCompact
+	}
+	b := n.Bytes()
+	i := len(b) - 1
+	for i >= 0 && b[i]&0x80 != 0 {
+		i--
+	}
+	if i < 0 {
+		// only the zero difficulty and value encode to no bytes at all
+		if len(b) != 0 {
+			return 0, nil, errCompact
+		}
+		return 0, nil, nil
+	}
+	if len(b)-i > 5 || len(b)-i == 5 && b[i] > 0x0f {
+		return 0, nil, errDifficultyTooLong
+	}
+	var d uint32
+	for _, c := range b[i:] {
+		d = d<<7 | uint32(c&0x7f)
+	}
+	x := b[:i]
+	if len(x) > f.MaxValueBytes {
+		return 0, nil, errValueTooLong
+	}
+	return d, x, nil
+}
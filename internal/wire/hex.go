@@ -0,0 +1,74 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+)
+
+// HexPrefix is prepended to Version to form the prefix of the hex encoding,
+// whose segments would otherwise also be valid base64.
+const HexPrefix = "h"
+
+// EncodeHex encodes a challenge of difficulty d and value bytes x like the
+// standard form but in lowercase hex, behind the prefix HexPrefix+Version.
+func (f Format) EncodeHex(d uint32, x []byte) string {
+	return HexPrefix + f.Version + "." + hex.EncodeToString(EncodeDifficulty(d)) + "." + hex.EncodeToString(x)
+}
+
+// EncodeSolutionHex encodes a solution with value bytes y in hex.
+func (f Format) EncodeSolutionHex(y []byte) string {
+	return HexPrefix + f.Version + "." + hex.EncodeToString(y)
+}
+
+// ParseChallengeHex is like ParseChallenge for the encoding produced by
+// EncodeHex, and enforces the same limits.
+func (f Format) ParseChallengeHex(v string) (uint32, []byte, error) {
+	parts, err := f.hexFormat().split(v, 3)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(parts[1]) > hex.EncodedLen(4) {
+		return 0, nil, errDifficultyTooLong
+	}
+	db, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: difficulty: %v", ErrBadEncoding, err)
+	}
+	d, err := DecodeDifficulty(db)
+	if err != nil {
+		return 0, nil, err
+	}
+	x, err := f.decodeHexValue(parts[2])
+	if err != nil {
+		return 0, nil, err
+	}
+	return d, x, nil
+}
+
+// ParseSolutionHex is like ParseSolution for the encoding produced by
+// EncodeSolutionHex.
+func (f Format) ParseSolutionHex(s string) ([]byte, error) {
+	parts, err := f.hexFormat().split(s, 2)
+	if err != nil {
+		return nil, err
+	}
+	return f.decodeHexValue(parts[1])
+}
+
+func (f Format) hexFormat() Format {
+	f.Version = HexPrefix + f.Version
+	return f
+}
+
+// decodeHexValue is like decodeValue for hex.
+func (f Format) decodeHexValue(v string) ([]byte, error) {
+	if len(v) > hex.EncodedLen(f.MaxValueBytes) {
+		return nil, errValueTooLong
+	}
+	b, err := hex.DecodeString(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: value: %v", ErrBadEncoding, err)
+	}
+	return bytes.TrimLeft(b, "\x00"), nil
+}
@@ -0,0 +1,222 @@
+// Package wire parses the redpwnpow text format shared by the gmp-backed pow
+// package and the gmp-free powverify package.
+package wire
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Format describes one version of the wire format.
+type Format struct {
+	// Version is the prefix of challenges and solutions in this format.
+	Version string
+	// MaxValueBytes is the length in bytes of the modulus. Challenge and
+	// solution values longer than this are rejected before they are
+	// decoded.
+	MaxValueBytes int
+}
+
+// Default is the original redpwnpow and kCTF format over 2^1279-1.
+var Default = Format{Version: "s", MaxValueBytes: 160}
+
+// Prefix returns the version prefix of an encoded challenge or solution.
+func Prefix(v string) string {
+	v = strings.TrimSpace(v)
+	if i := strings.IndexByte(v, '.'); i >= 0 {
+		return v[:i]
+	}
+	return v
+}
+
+// Every parse error matches one of these with errors.Is. The pow package
+// re-exports them.
+var (
+	ErrBadVersion      = errors.New("incorrect version")
+	ErrBadDifficulty   = errors.New("bad difficulty")
+	ErrBadEncoding     = errors.New("bad encoding")
+	ErrValueOutOfRange = errors.New("value out of range")
+)
+
+var (
+	errDifficultyTooLong = fmt.Errorf("%w: too long", ErrBadDifficulty)
+	errValueTooLong      = fmt.Errorf("%w: value too long", ErrValueOutOfRange)
+	errControl           = fmt.Errorf("%w: control character in input", ErrBadEncoding)
+	errSegments          = fmt.Errorf("%w: wrong number of segments", ErrBadEncoding)
+)
+
+// ParseChallenge splits a challenge string into its difficulty and the
+// big-endian bytes of its starting value.
+func (f Format) ParseChallenge(v string) (uint32, []byte, error) {
+	d, x, _, err := f.ParseChallengeParts(v)
+	return d, x, err
+}
+
+// Parts holds the segments of a challenge string and their decoded bytes as
+// sent, including any leading zero padding.
+type Parts struct {
+	Version         string
+	Difficulty      string // base64
+	Value           string // base64
+	DifficultyBytes []byte
+	ValueBytes      []byte
+}
+
+// ParseChallengeParts is like ParseChallenge but also returns the raw
+// components of the challenge.
+func (f Format) ParseChallengeParts(v string) (uint32, []byte, Parts, error) {
+	parts, err := f.splitChallenge(v)
+	if err != nil {
+		return 0, nil, Parts{}, err
+	}
+	p := Parts{Version: parts[0], Difficulty: parts[1], Value: parts[2]}
+	p.DifficultyBytes, err = f.decodeDifficultyBytes(p.Difficulty)
+	if err != nil {
+		return 0, nil, Parts{}, err
+	}
+	d, err := DecodeDifficulty(p.DifficultyBytes)
+	if err != nil {
+		return 0, nil, Parts{}, err
+	}
+	p.ValueBytes, err = f.decodeRawValue(p.Value)
+	if err != nil {
+		return 0, nil, Parts{}, err
+	}
+	return d, bytes.TrimLeft(p.ValueBytes, "\x00"), p, nil
+}
+
+// ParseDifficulty returns the difficulty of a challenge string without
+// decoding its value.
+func (f Format) ParseDifficulty(v string) (uint32, error) {
+	parts, err := f.splitChallenge(v)
+	if err != nil {
+		return 0, err
+	}
+	return f.decodeDifficulty(parts[1])
+}
+
+func (f Format) splitChallenge(v string) ([]string, error) {
+	return f.split(v, 3)
+}
+
+// split trims surrounding whitespace from v and splits it into exactly n
+// dot-separated segments, the first of which must be the version.
+func (f Format) split(v string, n int) ([]string, error) {
+	v = strings.TrimSpace(v)
+	if strings.IndexFunc(v, isControl) >= 0 {
+		return nil, errControl
+	}
+	parts := strings.Split(v, ".")
+	if parts[0] != f.Version {
+		return nil, fmt.Errorf("%w %q", ErrBadVersion, parts[0])
+	}
+	if len(parts) != n {
+		return nil, errSegments
+	}
+	return parts, nil
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+func (f Format) decodeDifficulty(v string) (uint32, error) {
+	b, err := f.decodeDifficultyBytes(v)
+	if err != nil {
+		return 0, err
+	}
+	return DecodeDifficulty(b)
+}
+
+func (f Format) decodeDifficultyBytes(v string) ([]byte, error) {
+	if len(v) > base64.StdEncoding.EncodedLen(4) {
+		return nil, errDifficultyTooLong
+	}
+	b, err := decodeBase64(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: difficulty: %v", ErrBadEncoding, err)
+	}
+	return b, nil
+}
+
+// EncodeDifficulty returns the canonical four-byte big-endian encoding of d.
+func EncodeDifficulty(d uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, d)
+	return b
+}
+
+// DecodeDifficulty decodes a big-endian difficulty of at most four
+// significant bytes.
+func DecodeDifficulty(b []byte) (uint32, error) {
+	// kCTF pads numbers to a multiple of three bytes, so ignore leading zeros
+	b = bytes.TrimLeft(b, "\x00")
+	if len(b) > 4 {
+		return 0, errDifficultyTooLong
+	}
+	// pad start with 0s to 4 bytes
+	b = append(make([]byte, 4-len(b)), b...)
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// ParseSolution returns the big-endian bytes of the value in a solution
+// string.
+func (f Format) ParseSolution(s string) ([]byte, error) {
+	parts, err := f.split(s, 2)
+	if err != nil {
+		return nil, err
+	}
+	return f.decodeValue(parts[1])
+}
+
+// decodeValue decodes a base64 value, rejecting anything longer than
+// f.MaxValueBytes without decoding it. Leading zero bytes, which kCTF emits to
+// pad numbers to a multiple of three bytes, do not count towards the limit and
+// are trimmed.
+func (f Format) decodeValue(v string) ([]byte, error) {
+	b, err := f.decodeRawValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimLeft(b, "\x00"), nil
+}
+
+// decodeRawValue is like decodeValue but keeps any leading zero bytes.
+func (f Format) decodeRawValue(v string) ([]byte, error) {
+	if len(v) > base64.StdEncoding.EncodedLen(f.MaxValueBytes) {
+		return nil, errValueTooLong
+	}
+	b, err := decodeBase64(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: value: %v", ErrBadEncoding, err)
+	}
+	if len(bytes.TrimLeft(b, "\x00")) > f.MaxValueBytes {
+		return nil, errValueTooLong
+	}
+	return b, nil
+}
+
+// decodeBase64 decodes standard or URL-safe base64, with or without padding,
+// since clients that relay challenges through shells and URLs often strip or
+// replace it.
+func decodeBase64(v string) ([]byte, error) {
+	if len(v)%4 == 0 {
+		v = strings.TrimSuffix(strings.TrimSuffix(v, "="), "=")
+	}
+	if strings.ContainsAny(v, "-_") {
+		return base64.RawURLEncoding.DecodeString(v)
+	}
+	return base64.RawStdEncoding.DecodeString(v)
+}
+
+// EncodeURL encodes a challenge of difficulty d and value bytes x like the
+// standard form, but in unpadded URL-safe base64 so that it survives query
+// strings unescaped.
+func (f Format) EncodeURL(d uint32, x []byte) string {
+	enc := base64.RawURLEncoding
+	return f.Version + "." + enc.EncodeToString(EncodeDifficulty(d)) + "." + enc.EncodeToString(x)
+}
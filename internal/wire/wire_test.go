@@ -0,0 +1,100 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestValueLengthLimit(t *testing.T) {
+	max := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, Default.MaxValueBytes))
+	padded := base64.StdEncoding.EncodeToString(append([]byte{0, 0}, bytes.Repeat([]byte{0xff}, Default.MaxValueBytes)...))
+	over := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, Default.MaxValueBytes+1))
+	huge := strings.Repeat("A", 10<<20)
+
+	for _, v := range []string{max, padded} {
+		if _, _, err := Default.ParseChallenge("s.AAAAAQ==." + v); err != nil {
+			t.Errorf("ParseChallenge with %d-char value: %v", len(v), err)
+		}
+		if _, err := Default.ParseSolution("s." + v); err != nil {
+			t.Errorf("ParseSolution with %d-char value: %v", len(v), err)
+		}
+	}
+	for _, v := range []string{over, huge} {
+		if _, _, err := Default.ParseChallenge("s.AAAAAQ==." + v); err != errValueTooLong {
+			t.Errorf("ParseChallenge with %d-char value: err = %v, want %v", len(v), err, errValueTooLong)
+		}
+		if _, err := Default.ParseSolution("s." + v); err != errValueTooLong {
+			t.Errorf("ParseSolution with %d-char value: err = %v, want %v", len(v), err, errValueTooLong)
+		}
+	}
+	if _, _, err := Default.ParseChallenge("s." + huge + ".AA=="); err != errDifficultyTooLong {
+		t.Errorf("ParseChallenge with huge difficulty: err = %v, want %v", err, errDifficultyTooLong)
+	}
+}
+
+func TestParseTrimsWhitespace(t *testing.T) {
+	for _, v := range []string{"s.AAFfkA==.wxZVoJ86n1h9CNavECXG4w==\n", " s.AAFfkA==.wxZVoJ86n1h9CNavECXG4w==\r\n", "\ts.AAFfkA==.wxZVoJ86n1h9CNavECXG4w== "} {
+		d, x, err := Default.ParseChallenge(v)
+		if err != nil || d != 90000 || len(x) != 16 {
+			t.Errorf("ParseChallenge(%q) = %d, %x, %v; want 90000, 16 bytes, nil", v, d, x, err)
+		}
+	}
+	if y, err := Default.ParseSolution("s.AAAB\n"); err != nil || !bytes.Equal(y, []byte{1}) {
+		t.Errorf("ParseSolution with trailing newline = %x, %v; want 01, nil", y, err)
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	for _, tc := range []struct {
+		v   string
+		err error
+	}{
+		{"s.AAFfkA==.wxZVoJ86n1h9CNavECXG4w==.AA==", errSegments},
+		{"s.AAFfkA==.wxZVoJ86n1h9CNavECXG4w==.", errSegments},
+		{"s.AAFfkA==", errSegments},
+		{"s.AAFfkA==.wxZVoJ86n1h9\nCNavECXG4w==", errControl},
+		{"s.AAFfkA==\x00.wxZVoJ86n1h9CNavECXG4w==", errControl},
+	} {
+		if _, _, err := Default.ParseChallenge(tc.v); err != tc.err {
+			t.Errorf("ParseChallenge(%q) error = %v, want %v", tc.v, err, tc.err)
+		}
+	}
+	for _, tc := range []struct {
+		v   string
+		err error
+	}{
+		{"s.AAAB.AAAB", errSegments},
+		{"s", errSegments},
+		{"s.AA\tAB", errControl},
+	} {
+		if _, err := Default.ParseSolution(tc.v); err != tc.err {
+			t.Errorf("ParseSolution(%q) error = %v, want %v", tc.v, err, tc.err)
+		}
+	}
+}
+
+func TestDifficultyCodec(t *testing.T) {
+	for _, d := range []uint32{0, 1, 255, 256, 90000, 1<<32 - 1} {
+		got, err := DecodeDifficulty(EncodeDifficulty(d))
+		if err != nil || got != d {
+			t.Errorf("DecodeDifficulty(EncodeDifficulty(%d)) = %d, %v", d, got, err)
+		}
+	}
+	for _, tc := range []struct {
+		b []byte
+		d uint32
+	}{
+		{nil, 0},
+		{[]byte{1, 0x5f, 0x90}, 90000},
+		{[]byte{0, 0, 0, 0, 0, 1}, 1},
+	} {
+		if d, err := DecodeDifficulty(tc.b); err != nil || d != tc.d {
+			t.Errorf("DecodeDifficulty(%x) = %d, %v; want %d, nil", tc.b, d, err, tc.d)
+		}
+	}
+	if _, err := DecodeDifficulty([]byte{1, 0, 0, 0, 0}); err != errDifficultyTooLong {
+		t.Errorf("DecodeDifficulty of 5 significant bytes error = %v, want %v", err, errDifficultyTooLong)
+	}
+}
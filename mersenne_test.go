@@ -0,0 +1,90 @@
+package pow
+
+import (
+	"testing"
+)
+
+// Reduction modulo a Mersenne number 2^n-1 needs no division: writing a
+// product as hi*2^n + lo, it is congruent to hi + lo. The helpers below
+// compute each iteration that way, as n-2 fold-reduced squarings, to compare
+// against gmp's generic Exp.
+//
+// With gmp every squaring then costs five cgo calls instead of a single
+// mpz_powm call for the whole exponentiation, and the call overhead
+// outweighs the cheaper reduction: on a Xeon, BenchmarkMersenneReduction
+// (d=1000) measured 1.27s for the fold path against 0.85s for Exp. Solve
+// therefore keeps using Exp.
+
+// mersenne holds scratch space for arithmetic modulo 2^n-1.
+type mersenne struct {
+	n    uint
+	m    *Int // 2^n-1, also the mask of the low n bits
+	t, u *Int
+}
+
+func newMersenne(n uint, m *Int) *mersenne {
+	return &mersenne{n: n, m: m, t: NewInt(0), u: NewInt(0)}
+}
+
+// sqrmod sets x to x^2 mod 2^n-1. x must be in [0, 2^n-1).
+func (r *mersenne) sqrmod(x *Int) {
+	r.t.Mul(x, x)
+	r.u.Rsh(r.t, r.n)
+	x.And(r.t, r.m)
+	x.Add(x, r.u)
+	if x.Cmp(r.m) >= 0 {
+		x.Sub(x, r.m)
+	}
+}
+
+// iterate performs one challenge iteration, x^(2^(n-2)) XOR 1.
+func (r *mersenne) iterate(x *Int) {
+	for i := uint(0); i < r.n-2; i++ {
+		r.sqrmod(x)
+	}
+	x.Xor(x, one)
+}
+
+func TestMersenneFoldMatchesExp(t *testing.T) {
+	for _, p := range []struct {
+		n      uint
+		params *Params
+	}{
+		{1279, ParamsP1279},
+		{2203, ParamsP2203},
+	} {
+		r := newMersenne(p.n, p.params.Modulus)
+		for _, v := range []int64{2, 3, 12345} {
+			c := &Challenge{d: 5, x: NewInt(v), p: p.params}
+			x := NewInt(v)
+			for i := uint32(0); i < c.d; i++ {
+				r.iterate(x)
+			}
+			if got, want := p.params.encodeSolution(x), c.Solve(); got != want {
+				t.Errorf("fold reduction for 2^%d-1, x=%d = %s, want %s", p.n, v, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkMersenneReduction(b *testing.B) {
+	const d = 1000
+	b.Run("Exp", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			x := NewInt(12345)
+			for j := 0; j < d; j++ {
+				x.Exp(x, exp, mod)
+				x.Xor(x, one)
+			}
+		}
+	})
+	b.Run("Fold", func(b *testing.B) {
+		r := newMersenne(1279, mod)
+		for i := 0; i < b.N; i++ {
+			x := NewInt(12345)
+			for j := 0; j < d; j++ {
+				r.iterate(x)
+			}
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package pow
+
+import (
+	"context"
+	"math/bits"
+	"runtime/pprof"
+	"strconv"
+	"time"
+)
+
+// SolveStats describes a single call to Solve or SolveContext.
+type SolveStats struct {
+	Difficulty uint32        // difficulty of the challenge
+	Iterations uint32        // iterations actually performed
+	Elapsed    time.Duration // wall time spent solving
+}
+
+// Rate returns the effective number of iterations per second.
+func (s SolveStats) Rate() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Iterations) / s.Elapsed.Seconds()
+}
+
+// SolveHook, if non-nil, is called once at the end of every Solve and
+// SolveContext, including cancelled ones. It is not called per iteration, so
+// it adds no cost to the solve loop itself. It must be safe for concurrent use
+// if challenges are solved concurrently, and should be set before solving
+// starts.
+var SolveHook func(SolveStats)
+
+// CheckStats describes a single call to Check or one of its variants.
+type CheckStats struct {
+	// Context is the context the check was given, or context.Background,
+	// so that hooks can attribute checks to clients through its values.
+	Context    context.Context
+	Difficulty uint32        // difficulty of the challenge
+	OK         bool          // whether the solution was accepted
+	Err        error         // why the check could not be completed, if it was not
+	Reason     Reason        // classifies OK and Err
+	Elapsed    time.Duration // wall time spent checking
+}
+
+// CheckHook, if non-nil, is called once at the end of every Check,
+// CheckContext and CheckLimit, and so of everything built on them such as
+// Server and Verifier, with the outcome and how long it took. Rejected
+// solutions have OK false and, unless the check could not be completed, a
+// nil Err. Like SolveHook it must be safe for concurrent use and should be
+// set before checking starts.
+var CheckHook func(CheckStats)
+
+// A Phase is a stage of Solve or Check reported to PhaseHook.
+type Phase int
+
+const (
+	// PhaseIterate is the iterations of a solve, or their inverses in a
+	// check. Exponentiation and XOR are not timed apart: the kernels fuse
+	// them, and the XOR only flips one bit.
+	PhaseIterate Phase = iota
+	// PhaseDecode is decoding the solution passed to Check.
+	PhaseDecode
+	// PhaseEncode is encoding a solution.
+	PhaseEncode
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseIterate:
+		return "iterate"
+	case PhaseDecode:
+		return "decode"
+	case PhaseEncode:
+		return "encode"
+	}
+	return "Phase(" + strconv.Itoa(int(p)) + ")"
+}
+
+// PhaseHook, if non-nil, is called at the end of each phase of Solve and
+// Check with the time it took, so that CPU time can be attributed in
+// production. Like SolveHook it must be safe for concurrent use and should be
+// set before solving starts. While it is nil, phases are not timed.
+var PhaseHook func(phase Phase, elapsed time.Duration)
+
+func reportPhase(hook func(Phase, time.Duration), phase Phase, start time.Time) {
+	hook(phase, time.Since(start))
+}
+
+// ProfileLabels, if set, makes Solve and Check run under runtime/pprof
+// labels, so that their samples in CPU profiles can be told apart:
+// pow_op ("solve" or "check"), pow_version, and pow_difficulty, the range of
+// powers of two the difficulty falls in, such as "1024-2047". As with
+// pprof.Do, the labels of the call's context are restored afterwards; calls
+// that take no context, such as Solve and Check, restore an empty set, so
+// callers that label their goroutines should use SolveContext and
+// CheckContext. It should be set before solving starts.
+var ProfileLabels = false
+
+// setLabels applies the profiling labels for op on c to the current
+// goroutine and returns the context whose labels to restore afterwards.
+func (c *Challenge) setLabels(ctx context.Context, op string) context.Context {
+	pprof.SetGoroutineLabels(pprof.WithLabels(ctx, pprof.Labels(
+		"pow_op", op,
+		"pow_version", c.params().Version,
+		"pow_difficulty", difficultyBucket(c.d),
+	)))
+	return ctx
+}
+
+// difficultyBucket names the range of powers of two d falls in.
+func difficultyBucket(d uint32) string {
+	if d == 0 {
+		return "0"
+	}
+	lo := uint64(1) << (bits.Len32(d) - 1)
+	return strconv.FormatUint(lo, 10) + "-" + strconv.FormatUint(2*lo-1, 10)
+}
@@ -0,0 +1,408 @@
+package pow
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSolveHook(t *testing.T) {
+	var got []SolveStats
+	SolveHook = func(s SolveStats) { got = append(got, s) }
+	defer func() { SolveHook = nil }()
+
+	c := &Challenge{d: 10, x: NewInt(12345)}
+	c.Solve()
+	if len(got) != 1 {
+		t.Fatalf("hook called %d times, want 1", len(got))
+	}
+	if got[0].Difficulty != 10 || got[0].Iterations != 10 {
+		t.Errorf("stats = %+v, want 10 iterations of difficulty 10", got[0])
+	}
+	if got[0].Elapsed <= 0 || got[0].Rate() <= 0 {
+		t.Errorf("stats = %+v, want positive elapsed time and rate", got[0])
+	}
+}
+
+func TestSolveContextCancelled(t *testing.T) {
+	var got SolveStats
+	SolveHook = func(s SolveStats) { got = s }
+	defer func() { SolveHook = nil }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := &Challenge{d: 100, x: NewInt(12345)}
+	if _, err := c.SolveContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SolveContext error = %v, want context.Canceled", err)
+	}
+	if got.Iterations != 0 || got.Difficulty != 100 {
+		t.Errorf("stats = %+v, want 0 iterations of difficulty 100", got)
+	}
+}
+
+func TestSolveContextMatchesSolve(t *testing.T) {
+	c := &Challenge{d: 20, x: NewInt(12345)}
+	s, err := c.SolveContext(context.Background())
+	if err != nil {
+		t.Fatalf("SolveContext failed: %v", err)
+	}
+	if want := c.solveOriginal(); s != want {
+		t.Errorf("SolveContext = %s, want %s", s, want)
+	}
+}
+
+func BenchmarkSolveHookUnset(b *testing.B) {
+	c := &Challenge{d: 10, x: NewInt(12345)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Solve()
+	}
+}
+
+func TestSolveDeadline(t *testing.T) {
+	c := &Challenge{d: 20, x: NewInt(12345)}
+	s, n, err := c.SolveDeadline(time.Minute)
+	if err != nil {
+		t.Fatalf("SolveDeadline failed: %v", err)
+	}
+	if n != 20 || s != c.solveOriginal() {
+		t.Errorf("SolveDeadline = %s, %d; want %s, 20", s, n, c.solveOriginal())
+	}
+
+	c = &Challenge{d: 1 << 30, x: NewInt(12345)}
+	s, n, err = c.SolveDeadline(50 * time.Millisecond)
+	if !errors.Is(err, ErrDeadlineExceeded) {
+		t.Fatalf("SolveDeadline error = %v, want ErrDeadlineExceeded", err)
+	}
+	if s != "" || n == 0 || n >= c.d {
+		t.Errorf("SolveDeadline = %q, %d; want no solution and partial progress", s, n)
+	}
+	if want := fmt.Sprintf("after %d of %d iterations", n, c.d); !strings.Contains(err.Error(), want) {
+		t.Errorf("SolveDeadline error = %q, want it to mention %q", err, want)
+	}
+}
+
+func TestSolveAsync(t *testing.T) {
+	c := &Challenge{d: 20, x: NewInt(12345)}
+	r, ok := <-c.SolveAsync(context.Background())
+	if !ok || r.Err != nil || r.Solution != c.Solve() || r.Elapsed <= 0 {
+		t.Errorf("SolveAsync = %+v, %v; want solution %s", r, ok, c.Solve())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := (&Challenge{d: 1 << 30, x: NewInt(12345)}).SolveAsync(ctx)
+	select {
+	case r := <-ch:
+		t.Fatalf("SolveAsync finished early: %+v", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+	cancel()
+	select {
+	case r := <-ch:
+		if !errors.Is(r.Err, context.Canceled) || r.Solution != "" {
+			t.Errorf("cancelled SolveAsync = %+v, want context.Canceled", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SolveAsync did not stop after cancellation")
+	}
+	if _, ok := <-ch; ok {
+		t.Error("SolveAsync channel not closed after the result")
+	}
+}
+
+func TestSolveAll(t *testing.T) {
+	cs := make([]*Challenge, 10)
+	for i := range cs {
+		cs[i] = GenerateChallenge(uint32(i % 4))
+	}
+	var calls []int
+	got, err := SolveAllProgress(context.Background(), cs, 3, func(done, total int) {
+		if total != len(cs) {
+			t.Errorf("progress total = %d, want %d", total, len(cs))
+		}
+		calls = append(calls, done)
+	})
+	if err != nil {
+		t.Fatalf("SolveAll: %v", err)
+	}
+	for i, c := range cs {
+		if want := c.Solve(); got[i] != want {
+			t.Errorf("solution %d = %s, want %s", i, got[i], want)
+		}
+	}
+	for i, done := range calls {
+		if done != i+1 {
+			t.Fatalf("progress calls = %v, want 1 to %d in order", calls, len(cs))
+		}
+	}
+	if len(calls) != len(cs) {
+		t.Errorf("%d progress calls, want %d", len(calls), len(cs))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	slow := []*Challenge{{d: 1, x: NewInt(12345)}, {d: 1 << 30, x: NewInt(12345)}}
+	got, err = SolveAll(ctx, slow, 0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("cancelled SolveAll error = %v, want context.DeadlineExceeded", err)
+	}
+	if got[0] != slow[0].Solve() || got[1] != "" {
+		t.Errorf("cancelled SolveAll = %q, want only the first solution", got)
+	}
+}
+
+func TestIncompleteError(t *testing.T) {
+	c := &Challenge{d: 400, x: NewInt(12345)}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := c.SolveContext(ctx)
+	var ie *IncompleteError
+	if !errors.As(err, &ie) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("SolveContext error = %v, want an *IncompleteError wrapping context.DeadlineExceeded", err)
+	}
+	if ie.Iterations >= c.d || ie.Difficulty != c.d {
+		t.Errorf("IncompleteError = %+v, want partial progress", ie)
+	}
+
+	// The state continues the solve where it stopped
+	s := c.Solver()
+	if err := s.Resume(ie.State); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if s.Iterations() != ie.Iterations {
+		t.Errorf("resumed at %d iterations, want %d", s.Iterations(), ie.Iterations)
+	}
+	for !s.Step(100) {
+	}
+	if got, _ := s.Solution(); got != c.solveOriginal() {
+		t.Errorf("resumed solution = %s, want %s", got, c.solveOriginal())
+	}
+
+	c = &Challenge{d: 1 << 30, x: NewInt(12345)}
+	if _, _, err := c.SolveDeadline(10 * time.Millisecond); !errors.As(err, &ie) || !errors.Is(err, ErrDeadlineExceeded) {
+		t.Errorf("SolveDeadline error = %v, want an *IncompleteError wrapping ErrDeadlineExceeded", err)
+	}
+}
+
+func TestCheckHook(t *testing.T) {
+	type clientKey struct{}
+	var got []CheckStats
+	CheckHook = func(s CheckStats) { got = append(got, s) }
+	defer func() { CheckHook = nil }()
+
+	c := GenerateChallenge(5)
+	ctx := context.WithValue(context.Background(), clientKey{}, "10.0.0.1")
+	c.CheckContext(ctx, c.Solve())
+	c.Check("s.AA==")
+	c.Check("s.!!!")
+	if len(got) != 3 {
+		t.Fatalf("CheckHook called %d times, want 3", len(got))
+	}
+	if s := got[0]; !s.OK || s.Err != nil || s.Difficulty != 5 || s.Elapsed <= 0 || s.Context.Value(clientKey{}) != "10.0.0.1" {
+		t.Errorf("accepted check reported %+v", s)
+	}
+	if s := got[1]; s.OK || s.Err != nil {
+		t.Errorf("rejected check reported %+v", s)
+	}
+	if s := got[2]; s.OK || !errors.Is(s.Err, ErrBadEncoding) {
+		t.Errorf("malformed check reported %+v", s)
+	}
+}
+
+func TestPhaseHook(t *testing.T) {
+	var mu sync.Mutex
+	phases := make(map[Phase]int)
+	PhaseHook = func(p Phase, elapsed time.Duration) {
+		mu.Lock()
+		phases[p]++
+		mu.Unlock()
+	}
+	defer func() { PhaseHook = nil }()
+
+	c := GenerateChallenge(5)
+	s := c.Solve()
+	if phases[PhaseIterate] != 1 || phases[PhaseEncode] != 1 || phases[PhaseDecode] != 0 {
+		t.Errorf("phases after Solve = %v, want one iterate and one encode", phases)
+	}
+	phases = make(map[Phase]int)
+	if ok, err := c.Check(s); !ok || err != nil {
+		t.Fatalf("Check = %v, %v", ok, err)
+	}
+	if phases[PhaseIterate] != 1 || phases[PhaseDecode] != 1 || phases[PhaseEncode] != 0 {
+		t.Errorf("phases after Check = %v, want one decode and one iterate", phases)
+	}
+	proof, err := c.SolveCheckpointed(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phases = make(map[Phase]int)
+	if ok, err := c.Check(proof.String()); !ok || err != nil {
+		t.Fatalf("Check(checkpoint proof) = %v, %v", ok, err)
+	}
+	if phases[PhaseIterate] != 1 || phases[PhaseDecode] != 1 || phases[PhaseEncode] != 0 {
+		t.Errorf("phases after checking a proof = %v, want one decode and one iterate", phases)
+	}
+	if got := PhaseEncode.String(); got != "encode" {
+		t.Errorf("PhaseEncode.String() = %q", got)
+	}
+}
+
+func TestProfileLabels(t *testing.T) {
+	ProfileLabels = true
+	defer func() { ProfileLabels = false }()
+
+	// Labels are only visible to the profiler, so this only checks that
+	// labelled calls still work
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("caller", "test"))
+	c := GenerateChallenge(3)
+	s, err := c.SolveContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := c.Check(s); !ok || err != nil {
+		t.Errorf("Check = %v, %v", ok, err)
+	}
+}
+
+func TestDifficultyBucket(t *testing.T) {
+	for d, want := range map[uint32]string{
+		0:         "0",
+		1:         "1-1",
+		5:         "4-7",
+		1024:      "1024-2047",
+		90000:     "65536-131071",
+		1<<32 - 1: "2147483648-4294967295",
+	} {
+		if got := difficultyBucket(d); got != want {
+			t.Errorf("difficultyBucket(%d) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestCheckAll(t *testing.T) {
+	var subs []Submission
+	var want []bool
+	for i := 0; i < 8; i++ {
+		c := GenerateChallenge(uint32(i % 3))
+		s := c.Solve()
+		if i%2 == 1 {
+			s = GenerateChallenge(5).Solve()
+		}
+		subs = append(subs, Submission{Challenge: c.String(), Solution: s})
+		want = append(want, i%2 == 0)
+	}
+	subs = append(subs, Submission{Challenge: "bogus", Solution: "s."})
+	results := CheckAll(context.Background(), subs, 3)
+	if len(results) != len(subs) {
+		t.Fatalf("%d results, want %d", len(results), len(subs))
+	}
+	for i, ok := range want {
+		if results[i].OK != ok || results[i].Err != nil {
+			t.Errorf("result %d = %+v, want OK %v", i, results[i], ok)
+		}
+	}
+	if r := results[len(subs)-1]; r.OK || !errors.Is(r.Err, ErrBadVersion) {
+		t.Errorf("result for a bad challenge = %+v, want ErrBadVersion", r)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i, r := range CheckAll(ctx, subs, 2) {
+		if r.OK || !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("cancelled result %d = %+v, want context.Canceled", i, r)
+		}
+	}
+}
+
+func TestCheckStream(t *testing.T) {
+	var in, want strings.Builder
+	for i := 0; i < 20; i++ {
+		c := GenerateChallenge(uint32(i % 4))
+		s, verdict := c.Solve(), "ok"
+		if i%3 == 1 {
+			s, verdict = GenerateChallenge(5).Solve(), "invalid"
+		}
+		fmt.Fprintf(&in, "%s\t%s\r\n", c, s)
+		fmt.Fprintf(&want, "%s\t%s\t%s\n", c, s, verdict)
+	}
+	in.WriteString("\nbogus\n")
+	want.WriteString("bogus\terror: no tab between challenge and solution\n")
+
+	var out strings.Builder
+	if err := CheckStream(context.Background(), strings.NewReader(in.String()), &out, 3); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want.String() {
+		t.Errorf("CheckStream wrote\n%s\nwant\n%s", out.String(), want.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := CheckStream(ctx, strings.NewReader(in.String()), io.Discard, 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled CheckStream error = %v, want context.Canceled", err)
+	}
+}
+
+func TestCheckStreamLongLine(t *testing.T) {
+	c := GenerateChallenge(400)
+	proof, err := c.SolveCheckpointed(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := c.String() + "\t" + proof.String()
+	if len(line) <= bufio.MaxScanTokenSize {
+		t.Fatalf("line of %d bytes fits the default scanner buffer", len(line))
+	}
+	var out strings.Builder
+	if err := CheckStream(context.Background(), strings.NewReader(line+"\n"), &out, 1); err != nil {
+		t.Fatal(err)
+	}
+	if want := line + "\tok\n"; out.String() != want {
+		t.Errorf("CheckStream wrote %.40q..., want the line followed by ok", out.String())
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestCheckStreamBlockedReader(t *testing.T) {
+	// The reader stays blocked in Read after the first line, so CheckStream
+	// must return without waiting for it
+	c := GenerateChallenge(1)
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go io.WriteString(pw, c.String()+"\t"+c.Solve()+"\n")
+
+	done := make(chan error, 1)
+	go func() { done <- CheckStream(context.Background(), pr, failingWriter{}, 2) }()
+	select {
+	case err := <-done:
+		if err == nil || err.Error() != "disk full" {
+			t.Errorf("CheckStream with a failing writer = %v, want disk full", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("CheckStream hung after a write error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { done <- CheckStream(ctx, pr, io.Discard, 2) }()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("cancelled CheckStream = %v, want context.Canceled", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("CheckStream hung after cancellation")
+	}
+}
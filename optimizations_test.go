@@ -5,12 +5,11 @@ import (
 	"fmt"
 	"testing"
 	"time"
-	"github.com/ncw/gmp"
 )
 
 // solveOriginal implements the original unoptimized version of Solve for performance comparison
 func (c *Challenge) solveOriginal() string {
-	x := gmp.NewInt(0).Set(c.x) // dont mutate c.x
+	x := NewInt(0).Set(c.x) // dont mutate c.x
 	for i := uint32(0); i < c.d; i++ {
 		x.Exp(x, exp, mod)
 		x.Xor(x, one)
@@ -22,7 +21,7 @@ func (c *Challenge) solveOriginal() string {
 func BenchmarkPerformanceComparison(b *testing.B) {
 	// Edge case with zero - should show massive improvement
 	b.Run("EdgeCase_Zero_d1000", func(b *testing.B) {
-		c := &Challenge{d: 1000, x: gmp.NewInt(0)}
+		c := &Challenge{d: 1000, x: NewInt(0)}
 		
 		b.Run("Optimized", func(b *testing.B) {
 			b.ResetTimer()
@@ -41,7 +40,7 @@ func BenchmarkPerformanceComparison(b *testing.B) {
 	
 	// Edge case with one - should show massive improvement
 	b.Run("EdgeCase_One_d1000", func(b *testing.B) {
-		c := &Challenge{d: 1000, x: gmp.NewInt(1)}
+		c := &Challenge{d: 1000, x: NewInt(1)}
 		
 		b.Run("Optimized", func(b *testing.B) {
 			b.ResetTimer()
@@ -60,7 +59,7 @@ func BenchmarkPerformanceComparison(b *testing.B) {
 	
 	// Small difficulty with loop unrolling - should show minor improvement
 	b.Run("SmallDifficulty_d3", func(b *testing.B) {
-		c := &Challenge{d: 3, x: gmp.NewInt(12345)}
+		c := &Challenge{d: 3, x: NewInt(12345)}
 		
 		b.Run("Optimized", func(b *testing.B) {
 			b.ResetTimer()
@@ -79,7 +78,7 @@ func BenchmarkPerformanceComparison(b *testing.B) {
 	
 	// Regular case - should show no significant difference
 	b.Run("Regular_d10", func(b *testing.B) {
-		c := &Challenge{d: 10, x: gmp.NewInt(12345)}
+		c := &Challenge{d: 10, x: NewInt(12345)}
 		
 		b.Run("Optimized", func(b *testing.B) {
 			b.ResetTimer()
@@ -104,7 +103,7 @@ func TestPerformanceImprovements(t *testing.T) {
 		
 		for _, d := range difficulties {
 			t.Run(fmt.Sprintf("Zero_d%d", d), func(t *testing.T) {
-				c := &Challenge{d: d, x: gmp.NewInt(0)}
+				c := &Challenge{d: d, x: NewInt(0)}
 				
 				// Measure optimized version
 				start := time.Now()
@@ -141,7 +140,7 @@ func TestPerformanceImprovements(t *testing.T) {
 	t.Run("LoopUnrollingPerformance", func(t *testing.T) {
 		for d := uint32(1); d <= 4; d++ {
 			t.Run(fmt.Sprintf("d%d", d), func(t *testing.T) {
-				c := &Challenge{d: d, x: gmp.NewInt(12345)}
+				c := &Challenge{d: d, x: NewInt(12345)}
 				
 				// Measure optimized version
 				start := time.Now()
@@ -170,7 +169,7 @@ func TestPerformanceImprovements(t *testing.T) {
 func BenchmarkEdgeCasePerformance(b *testing.B) {
 	// Test the optimization for values that hit the fast path
 	b.Run("Zero_HighDifficulty", func(b *testing.B) {
-		c := &Challenge{d: 10000, x: gmp.NewInt(0)}
+		c := &Challenge{d: 10000, x: NewInt(0)}
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
 			c.Solve()
@@ -178,7 +177,7 @@ func BenchmarkEdgeCasePerformance(b *testing.B) {
 	})
 	
 	b.Run("One_HighDifficulty", func(b *testing.B) {
-		c := &Challenge{d: 10000, x: gmp.NewInt(1)}
+		c := &Challenge{d: 10000, x: NewInt(1)}
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
 			c.Solve()
@@ -187,7 +186,7 @@ func BenchmarkEdgeCasePerformance(b *testing.B) {
 	
 	// Compare with a regular case for the same difficulty
 	b.Run("Regular_HighDifficulty", func(b *testing.B) {
-		c := &Challenge{d: 10000, x: gmp.NewInt(12345)}
+		c := &Challenge{d: 10000, x: NewInt(12345)}
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
 			c.Solve()
@@ -208,7 +207,7 @@ func TestCorrectness(t *testing.T) {
 	}
 	
 	for _, tc := range testCases {
-		c := &Challenge{d: tc.d, x: gmp.NewInt(tc.x)}
+		c := &Challenge{d: tc.d, x: NewInt(tc.x)}
 		
 		// Test optimized version
 		optimizedSolution := c.Solve()
@@ -228,4 +227,33 @@ func TestCorrectness(t *testing.T) {
 				tc.d, tc.x, optimizedSolution, originalSolution)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// BenchmarkSolveBlock compares block sizes for the solve loop
+func BenchmarkSolveBlock(b *testing.B) {
+	defer func(old uint32) { solveBlock = old }(solveBlock)
+	c := &Challenge{d: 64, x: NewInt(12345)}
+	for _, n := range []uint32{1, 2, 4, 8, 16, 64} {
+		b.Run(fmt.Sprintf("block%d", n), func(b *testing.B) {
+			solveBlock = n
+			for i := 0; i < b.N; i++ {
+				c.Solve()
+			}
+		})
+	}
+}
+
+// TestSolveBlockSizes checks that the block size does not change the result
+func TestSolveBlockSizes(t *testing.T) {
+	defer func(old uint32) { solveBlock = old }(solveBlock)
+	for _, d := range []uint32{1, 3, 4, 5, 8, 9, 17} {
+		c := &Challenge{d: d, x: NewInt(12345)}
+		want := c.solveOriginal()
+		for _, n := range []uint32{1, 3, 8} {
+			solveBlock = n
+			if got := c.Solve(); got != want {
+				t.Errorf("Solve() with d=%d and block size %d = %s, want %s", d, n, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package pow
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// p1279 is an element of the field 2^1279-1 as 20 little-endian 64-bit limbs.
+// Between squarings it is only partially reduced: any value below 2^1280 is
+// allowed, and normalize brings it into [0, 2^1279-1).
+//
+// Reduction modulo a Mersenne prime needs no division. Writing a product as
+// hi*2^1279 + lo, it is congruent to hi + lo, so each squaring is a
+// fixed-width schoolbook square followed by a shift-and-add fold. Unlike the
+// fold in mersenne_test.go, which goes through the generic Int operations,
+// this stays in Go for all 1277 squarings of an iteration.
+//
+// There is no arm64 assembly. The compiler already lowers bits.Mul64 to MUL
+// and UMULH and bits.Add64 to ADDS and ADCS there, which is what an
+// assembly kernel would use. NEON has no 64x64->128-bit multiply, so it
+// cannot vectorize the limb products the way IFMA does on amd64.
+type p1279 [p1279Limbs]uint64
+
+const (
+	p1279Limbs = 20
+	p1279Bytes = p1279Limbs * 8
+	p1279Top   = 1<<63 - 1 // mask of the low 1279-64*19 bits of the top limb
+)
+
+// setInt sets z to x, which must not be negative. Values of 2^1280 or more
+// do not fit the limbs and are reduced modulo 2^1279-1 first.
+func (z *p1279) setInt(x *Int) {
+	if x.BitLen() > 64*p1279Limbs {
+		r := getInt()
+		defer putInt(r)
+		x = r.Mod(x, ParamsP1279.Modulus)
+	}
+	var b, vb [p1279Bytes]byte
+	xb := appendValueBytes(vb[:0], x)
+	copy(b[p1279Bytes-len(xb):], xb)
+	for i := range z {
+		z[i] = binary.BigEndian.Uint64(b[p1279Bytes-8*(i+1):])
+	}
+}
+
+// int sets x to the normalized value of z and returns it.
+func (z *p1279) int(x *Int) *Int {
+	z.normalize()
+	var b [p1279Bytes]byte
+	for i := range z {
+		binary.BigEndian.PutUint64(b[p1279Bytes-8*(i+1):], z[i])
+	}
+	return x.SetBytes(b[:])
+}
+
+// square sets z to z^2 mod 2^1279-1, partially reduced.
+func (z *p1279) square() {
+	var t [2 * p1279Limbs]uint64
+
+	// Products of distinct limbs, each needed twice
+	for i := 0; i < p1279Limbs; i++ {
+		var carry uint64
+		for j := i + 1; j < p1279Limbs; j++ {
+			hi, lo := bits.Mul64(z[i], z[j])
+			var c uint64
+			lo, c = bits.Add64(lo, t[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+			t[i+j] = lo
+			carry = hi
+		}
+		t[i+p1279Limbs] = carry
+	}
+	for i := len(t) - 1; i > 0; i-- {
+		t[i] = t[i]<<1 | t[i-1]>>63
+	}
+	t[0] <<= 1
+
+	// Squares of each limb
+	var c uint64
+	for i := 0; i < p1279Limbs; i++ {
+		hi, lo := bits.Mul64(z[i], z[i])
+		t[2*i], c = bits.Add64(t[2*i], lo, c)
+		t[2*i+1], c = bits.Add64(t[2*i+1], hi, c)
+	}
+
+	// Fold: lo is the low 1279 bits of t, hi the rest, shifted down
+	c = 0
+	for i := 0; i < p1279Limbs; i++ {
+		hi := t[i+p1279Limbs-1]>>63 | t[i+p1279Limbs]<<1
+		lo := t[i]
+		if i == p1279Limbs-1 {
+			lo &= p1279Top
+		}
+		z[i], c = bits.Add64(lo, hi, c)
+	}
+	// The fold leaves at most a few bits above 2^1279; fold them again
+	z.carry(z[p1279Limbs-1]>>63 + (c+t[2*p1279Limbs-1]>>63)<<1)
+}
+
+// carry clears the top bit of z and adds extra to it.
+func (z *p1279) carry(extra uint64) {
+	z[p1279Limbs-1] &= p1279Top
+	var c uint64
+	z[0], c = bits.Add64(z[0], extra, 0)
+	for i := 1; c != 0 && i < p1279Limbs; i++ {
+		z[i], c = bits.Add64(z[i], 0, c)
+	}
+}
+
+// normalize fully reduces z into [0, 2^1279-1).
+func (z *p1279) normalize() {
+	// Fold the top bit, leaving z < 2^1279 + 1
+	z.carry(z[p1279Limbs-1] >> 63)
+	if z[p1279Limbs-1]>>63 != 0 {
+		z.carry(1)
+	}
+	// z is now below 2^1279; the only unreduced value left is 2^1279-1
+	if z[p1279Limbs-1] != p1279Top {
+		return
+	}
+	for _, l := range z[:p1279Limbs-1] {
+		if l != 1<<64-1 {
+			return
+		}
+	}
+	*z = p1279{}
+}
+
+// iterate1279Generic runs n challenge iterations, x^(2^1277) XOR 1 mod
+// 2^1279-1, on x in place. x must not be negative.
+func iterate1279Generic(x *Int, n uint32) {
+	var z p1279
+	z.setInt(x)
+	for ; n > 0; n-- {
+		for i := 0; i < 1277; i++ {
+			z.square()
+		}
+		z.normalize()
+		z[0] ^= 1
+	}
+	z.int(x)
+}
@@ -0,0 +1,145 @@
+//go:build amd64 && !purego
+
+package pow
+
+// On CPUs with AVX-512 IFMA, iterations over 2^1279-1 square in radix 2^52:
+// x is held as 25 limbs of 52 bits, the form vpmadd52luq and vpmadd52huq
+// multiply, and sqr1279IFMA accumulates the 50 product columns eight at a
+// time. Carrying and the Mersenne fold stay in Go. Between squarings x is
+// only partially reduced, to below 2^1279 + 2^43.
+
+const (
+	ifmaLimbs = 25
+	ifmaMask  = 1<<52 - 1
+	// ifmaPad is the number of zero limbs on either side of x in the
+	// squaring input, so that sqr1279IFMA can load shifted vectors of
+	// limbs without bounds checks.
+	ifmaPad = 32
+)
+
+// ifmaCols holds the low halves of the product columns in [0, 56) and the
+// high halves, which belong one column up, in [56, 112).
+type ifmaCols [112]uint64
+
+// ifmaInput is x surrounded by ifmaPad zero limbs on the left and enough on
+// the right to fill the last vector.
+type ifmaInput [ifmaPad + ifmaLimbs + 31]uint64
+
+// sqr1279IFMA sets c to the columns of the square of the limbs in a.
+//
+//go:noescape
+func sqr1279IFMA(c *ifmaCols, a *ifmaInput)
+
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+func xgetbv() (eax, edx uint32)
+
+func init() {
+	if hasIFMA() {
+		kernels = append(kernels, kernel{"avx512ifma", iterate1279IFMA})
+	}
+}
+
+// hasIFMA reports whether the CPU and operating system support AVX-512F
+// and AVX-512 IFMA.
+func hasIFMA() bool {
+	if max, _, _, _ := cpuid(0, 0); max < 7 {
+		return false
+	}
+	// The OS must save the opmask and ZMM registers
+	if _, _, ecx, _ := cpuid(1, 0); ecx&(1<<27) == 0 {
+		return false
+	}
+	if eax, _ := xgetbv(); eax&0xe6 != 0xe6 {
+		return false
+	}
+	_, ebx, _, _ := cpuid(7, 0)
+	return ebx&(1<<16) != 0 && ebx&(1<<21) != 0
+}
+
+func iterate1279IFMA(x *Int, n uint32) {
+	var in ifmaInput
+	var c ifmaCols
+	var z p1279
+	a := (*[ifmaLimbs]uint64)(in[ifmaPad : ifmaPad+ifmaLimbs])
+	z.setInt(x)
+	z.to52(a)
+	for ; n > 0; n-- {
+		for i := 0; i < 1277; i++ {
+			sqr1279IFMA(&c, &in)
+			reduce52(a, &c)
+		}
+		z.set52(a)
+		z.normalize()
+		z[0] ^= 1
+		z.to52(a)
+	}
+	z.int(x)
+}
+
+// reduce52 sets a to the partially reduced value of the columns c.
+func reduce52(a *[ifmaLimbs]uint64, c *ifmaCols) {
+	// Carry the columns into 51 limbs of 52 bits
+	var t [2*ifmaLimbs + 1]uint64
+	var carry uint64
+	for k := 0; k < 2*ifmaLimbs; k++ {
+		s := c[k] + carry
+		if k > 0 {
+			s += c[56+k-1]
+		}
+		t[k] = s & ifmaMask
+		carry = s >> 52
+	}
+	t[2*ifmaLimbs] = carry
+
+	// Fold: add t >> 1279 to the low 1279 bits of t. Bit 1279 is bit 31
+	// of limb 24.
+	var r [ifmaLimbs + 1]uint64
+	copy(r[:], t[:ifmaLimbs])
+	r[ifmaLimbs-1] &= 1<<31 - 1
+	for k := range r {
+		r[k] += t[k+ifmaLimbs-1]>>31 | t[k+ifmaLimbs]<<21&ifmaMask
+	}
+	carry = 0
+	for k := range r {
+		r[k] += carry
+		carry = r[k] >> 52
+		r[k] &= ifmaMask
+	}
+
+	// The square is below 2^2600, so the sum is below 2^1322 and fits in
+	// r. Fold the bits above 1279 once more.
+	h := r[ifmaLimbs-1]>>31 | r[ifmaLimbs]<<21
+	r[ifmaLimbs-1] &= 1<<31 - 1
+	carry = h
+	for k := 0; k < ifmaLimbs; k++ {
+		a[k] = r[k] + carry
+		carry = a[k] >> 52
+		a[k] &= ifmaMask
+	}
+}
+
+// to52 sets a to the limbs of z in radix 2^52.
+func (z *p1279) to52(a *[ifmaLimbs]uint64) {
+	for i := range a {
+		w, s := 52*i/64, uint(52*i%64)
+		l := z[w] >> s
+		if s > 12 && w+1 < p1279Limbs {
+			l |= z[w+1] << (64 - s)
+		}
+		a[i] = l & ifmaMask
+	}
+}
+
+// set52 sets z to the value of the radix 2^52 limbs in a, which must be
+// smaller than 2^1280.
+func (z *p1279) set52(a *[ifmaLimbs]uint64) {
+	*z = p1279{}
+	for i, l := range a {
+		w, s := 52*i/64, uint(52*i%64)
+		z[w] |= l << s
+		if s > 12 && w+1 < p1279Limbs {
+			z[w+1] |= l >> (64 - s)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func sqr1279IFMA(c *ifmaCols, a *ifmaInput)
+//
+// For each limb a_i, the low and high halves of a_i times the limbs a_(k-i)
+// are added to columns k in Z0-Z6 and Z7-Z13, eight columns per register.
+// The vectors of a_(k-i) are unaligned loads from the zero-padded input.
+TEXT ·sqr1279IFMA(SB), NOSPLIT, $0-16
+	MOVQ c+0(FP), DI
+	MOVQ a+8(FP), SI
+
+	VPXORQ Z0, Z0, Z0
+	VPXORQ Z1, Z1, Z1
+	VPXORQ Z2, Z2, Z2
+	VPXORQ Z3, Z3, Z3
+	VPXORQ Z4, Z4, Z4
+	VPXORQ Z5, Z5, Z5
+	VPXORQ Z6, Z6, Z6
+	VPXORQ Z7, Z7, Z7
+	VPXORQ Z8, Z8, Z8
+	VPXORQ Z9, Z9, Z9
+	VPXORQ Z10, Z10, Z10
+	VPXORQ Z11, Z11, Z11
+	VPXORQ Z12, Z12, Z12
+	VPXORQ Z13, Z13, Z13
+
+	// BX points at a_i, R8 at a_(-i), starting from i = 0
+	LEAQ 256(SI), BX
+	MOVQ BX, R8
+	MOVQ $25, CX
+
+loop:
+	VPBROADCASTQ (BX), Z14
+	VMOVDQU64    0(R8), Z15
+	VPMADD52LUQ  Z15, Z14, Z0
+	VPMADD52HUQ  Z15, Z14, Z7
+	VMOVDQU64    64(R8), Z15
+	VPMADD52LUQ  Z15, Z14, Z1
+	VPMADD52HUQ  Z15, Z14, Z8
+	VMOVDQU64    128(R8), Z15
+	VPMADD52LUQ  Z15, Z14, Z2
+	VPMADD52HUQ  Z15, Z14, Z9
+	VMOVDQU64    192(R8), Z15
+	VPMADD52LUQ  Z15, Z14, Z3
+	VPMADD52HUQ  Z15, Z14, Z10
+	VMOVDQU64    256(R8), Z15
+	VPMADD52LUQ  Z15, Z14, Z4
+	VPMADD52HUQ  Z15, Z14, Z11
+	VMOVDQU64    320(R8), Z15
+	VPMADD52LUQ  Z15, Z14, Z5
+	VPMADD52HUQ  Z15, Z14, Z12
+	VMOVDQU64    384(R8), Z15
+	VPMADD52LUQ  Z15, Z14, Z6
+	VPMADD52HUQ  Z15, Z14, Z13
+	ADDQ $8, BX
+	SUBQ $8, R8
+	DECQ CX
+	JNZ  loop
+
+	VMOVDQU64 Z0, 0(DI)
+	VMOVDQU64 Z1, 64(DI)
+	VMOVDQU64 Z2, 128(DI)
+	VMOVDQU64 Z3, 192(DI)
+	VMOVDQU64 Z4, 256(DI)
+	VMOVDQU64 Z5, 320(DI)
+	VMOVDQU64 Z6, 384(DI)
+	VMOVDQU64 Z7, 448(DI)
+	VMOVDQU64 Z8, 512(DI)
+	VMOVDQU64 Z9, 576(DI)
+	VMOVDQU64 Z10, 640(DI)
+	VMOVDQU64 Z11, 704(DI)
+	VMOVDQU64 Z12, 768(DI)
+	VMOVDQU64 Z13, 832(DI)
+	VZEROUPPER
+	RET
+
+// func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL eaxArg+0(FP), AX
+	MOVL ecxArg+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
+
+// func xgetbv() (eax, edx uint32)
+TEXT ·xgetbv(SB), NOSPLIT, $0-8
+	MOVL $0, CX
+	XGETBV
+	MOVL AX, eax+0(FP)
+	MOVL DX, edx+4(FP)
+	RET
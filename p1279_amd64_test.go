@@ -0,0 +1,43 @@
+//go:build amd64 && !purego
+
+package pow
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func TestIterate1279IFMA(t *testing.T) {
+	if !hasIFMA() {
+		t.Skip("CPU does not support AVX-512 IFMA")
+	}
+	p := ParamsP1279
+	pm1 := NewInt(0).Sub(p.Modulus, one)
+	values := []*Int{NewInt(0), NewInt(1), NewInt(2), pm1, NewInt(0).Set(p.Modulus)}
+	for i := 0; i < 8; i++ {
+		b := make([]byte, 160)
+		if _, err := rand.Read(b); err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, NewInt(0).Mod(NewInt(0).SetBytes(b), p.Modulus))
+	}
+	for _, v := range values {
+		want := NewInt(0).Set(v)
+		iterate1279Generic(want, 3)
+		got := NewInt(0).Set(v)
+		iterate1279IFMA(got, 3)
+		if got.Cmp(want) != 0 {
+			t.Fatalf("iterate1279IFMA(%x) = %x, want %x", v, got, want)
+		}
+	}
+}
+
+func BenchmarkIterate1279IFMA(b *testing.B) {
+	if !hasIFMA() {
+		b.Skip("CPU does not support AVX-512 IFMA")
+	}
+	x := NewInt(0).Set(GenerateChallenge(1).value())
+	for i := 0; i < b.N; i++ {
+		iterate1279IFMA(x, 1)
+	}
+}
@@ -0,0 +1,90 @@
+package pow
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func TestKernelsMatchExp(t *testing.T) {
+	p := ParamsP1279
+	pm1 := NewInt(0).Sub(p.Modulus, one)
+	values := []*Int{NewInt(0), NewInt(1), NewInt(2), pm1, NewInt(0).Set(p.Modulus)}
+	for i := 0; i < 8; i++ {
+		b := make([]byte, 160)
+		if _, err := rand.Read(b); err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, NewInt(0).Mod(NewInt(0).SetBytes(b), p.Modulus))
+	}
+	for _, v := range values {
+		want := NewInt(0).Set(v)
+		for i := 0; i < 3; i++ {
+			want.Exp(want, p.Exponent, p.Modulus)
+			want.Xor(want, one)
+		}
+		for _, k := range kernels {
+			got := NewInt(0).Set(v)
+			k.iterate(got, 3)
+			if got.Cmp(want) != 0 {
+				t.Fatalf("%s kernel: iterate(%x) = %x, want %x", k.name, v, got, want)
+			}
+		}
+	}
+}
+
+func TestKernelsReduceOversizedValues(t *testing.T) {
+	p := ParamsP1279
+	v := NewInt(0).Lsh(p.Modulus, 8)
+	v.Add(v, NewInt(5))
+	want := NewInt(5)
+	p.iterateExp(want, 2)
+	for _, k := range kernels {
+		got := NewInt(0).Set(v)
+		k.iterate(got, 2)
+		if got.Cmp(want) != 0 {
+			t.Errorf("%s kernel: iterate(p<<8+5) = %x, want %x", k.name, got, want)
+		}
+	}
+	c := &Challenge{d: 2, x: v}
+	if got, want := c.Solve(), (&Challenge{d: 2, x: NewInt(5)}).Solve(); got != want {
+		t.Errorf("Solve with x = p<<8+5 = %s, want %s", got, want)
+	}
+}
+
+func TestBackend(t *testing.T) {
+	b := Backend()
+	for _, k := range kernels {
+		if k.name == b {
+			return
+		}
+	}
+	t.Fatalf("Backend() = %q, not one of the kernels", b)
+}
+
+func TestP1279SquareReduces(t *testing.T) {
+	// 2^1280-1, the largest partially reduced value, is 1 mod p
+	var z p1279
+	for i := range z {
+		z[i] = 1<<64 - 1
+	}
+	z.square()
+	if got := z.int(NewInt(0)); got.Cmp(NewInt(1)) != 0 {
+		t.Fatalf("(2^1280-1)^2 = %x, want 1", got)
+	}
+}
+
+func BenchmarkIterate1279Generic(b *testing.B) {
+	x := NewInt(0).Set(GenerateChallenge(1).value())
+	for i := 0; i < b.N; i++ {
+		iterate1279Generic(x, 1)
+	}
+}
+
+func BenchmarkIterateExp(b *testing.B) {
+	p := ParamsP1279
+	x := NewInt(0).Set(GenerateChallenge(1).value())
+	for i := 0; i < b.N; i++ {
+		x.Exp(x, p.Exponent, p.Modulus)
+		x.Xor(x, one)
+	}
+}
@@ -0,0 +1,172 @@
+package pow
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/redpwn/pow/internal/wire"
+)
+
+// Params describes the field a challenge is computed in. Each iteration maps
+// x to x^Exponent XOR 1 mod Modulus. Params must not be modified once in use.
+type Params struct {
+	// Modulus is a prime p with p = 3 mod 4.
+	Modulus *Int
+	// Exponent is (p+1)/4, so that x^Exponent is a square root of x.
+	Exponent *Int
+	// Version is the wire format prefix identifying these parameters.
+	Version string
+
+	// edges holds the encoded solutions 0 and 1, which Solve returns for
+	// challenges with x of 0 or 1. It is empty for Params not made by
+	// mersenneParams.
+	edges [2]string
+}
+
+var (
+	// ParamsP1279 is the default field 2^1279-1, compatible with
+	// redpwnpow and kCTF. Its challenges use the version prefix "s".
+	ParamsP1279 = mersenneParams(version, 1279)
+	// ParamsP2203 is the field 2^2203-1. Each iteration costs roughly
+	// three times as much as with ParamsP1279. Its challenges use the
+	// version prefix "s2203".
+	ParamsP2203 = mersenneParams("s2203", 2203)
+)
+
+// mersenneParams returns the parameters for the Mersenne prime 2^n-1, for
+// which the square root exponent is 2^(n-2).
+func mersenneParams(version string, n uint) *Params {
+	m := NewInt(0).Lsh(one, n)
+	m.Sub(m, one)
+	p := &Params{
+		Modulus:  m,
+		Exponent: NewInt(0).Lsh(one, n-2),
+		Version:  version,
+	}
+	p.edges = [2]string{p.encodeSolution(zero), p.encodeSolution(one)}
+	return p
+}
+
+// ErrInvalidParams is returned for parameters that cannot be used; see
+// Params.Validate.
+var ErrInvalidParams = errors.New("invalid params")
+
+// NewMersenneParams returns the parameters for the Mersenne prime 2^n-1,
+// identified on the wire by version. It returns ErrInvalidParams if 2^n-1 is
+// not prime or version is not usable.
+func NewMersenneParams(version string, n uint) (*Params, error) {
+	if n < 3 {
+		return nil, fmt.Errorf("%w: 2^%d-1 is too small", ErrInvalidParams, n)
+	}
+	p := mersenneParams(version, n)
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate returns ErrInvalidParams unless Modulus is a prime congruent to 3
+// mod 4, Exponent is (Modulus+1)/4, and Version is a non-empty string without
+// dots or spaces. While RejectTwinSolutions is set, Modulus must also be
+// congruent to 7 mod 8.
+func (p *Params) Validate() error {
+	if !validVersion(p.Version) {
+		return fmt.Errorf("%w: version %q", ErrInvalidParams, p.Version)
+	}
+	if p.Modulus == nil || p.Modulus.Cmp(two) <= 0 || p.Modulus.Bit(0) != 1 || p.Modulus.Bit(1) != 1 || !p.Modulus.ProbablyPrime(20) {
+		return fmt.Errorf("%w: modulus is not a prime congruent to 3 mod 4", ErrInvalidParams)
+	}
+	e := NewInt(0).Add(p.Modulus, one)
+	if p.Exponent == nil || e.Rsh(e, 2).Cmp(p.Exponent) != 0 {
+		return fmt.Errorf("%w: exponent is not (modulus+1)/4", ErrInvalidParams)
+	}
+	if RejectTwinSolutions && !p.detectsTwins() {
+		return errTwinModulus
+	}
+	return nil
+}
+
+func validVersion(v string) bool {
+	return v != "" && !strings.ContainsAny(v, ". \t\r\n")
+}
+
+// NewChallengeWithParams is like NewChallenge for the field described by p.
+// It returns ErrInvalidParams if p is not valid. Challenges with parameters
+// other than the presets can be encoded, solved and checked, but
+// DecodeChallenge only recognizes them once they are registered with
+// RegisterScheme.
+func NewChallengeWithParams(p *Params, d uint32, x []byte) (*Challenge, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p.newChallenge(d, x, math.MaxUint32)
+}
+
+// GenerateChallenge creates a new random challenge in the field described
+// by p. The value has 16 random bytes, or as many as the modulus has if that
+// is fewer. In the latter case values that would not be smaller than the
+// modulus, or would be weak (see IsWeak), are drawn again, as they are too
+// likely to leave alone; modulo 3, where every value is weak, only the first
+// check applies.
+func (p *Params) GenerateChallenge(d uint32) *Challenge {
+	n := p.format().MaxValueBytes
+	if n > 16 {
+		c := GenerateChallenge(d)
+		c.p = p
+		return c
+	}
+	b := make([]byte, n)
+	c := &Challenge{d: d, x: NewInt(0), p: p}
+	for {
+		if _, err := rand.Read(b); err != nil {
+			panic(err)
+		}
+		b[0] &= byte(1<<(p.Modulus.BitLen()-8*(n-1)) - 1)
+		if c.x.SetBytes(b).Cmp(p.Modulus) < 0 && (!c.IsWeak() || p.Modulus.BitLen() == 2) {
+			return c
+		}
+	}
+}
+
+// GenerateChallengeSize is like GenerateChallenge but draws a random value of
+// size bytes instead of 16, from 1 up to the byte length of the modulus. It
+// returns ErrValueOutOfRange for other sizes. Values that would not be
+// smaller than the modulus, or would be weak (see IsWeak), are drawn again.
+func (p *Params) GenerateChallengeSize(d uint32, size int) (*Challenge, error) {
+	n := p.format().MaxValueBytes
+	if size < 1 || size > n {
+		return nil, fmt.Errorf("%w: size %d not between 1 and %d", ErrValueOutOfRange, size, n)
+	}
+	b := make([]byte, size)
+	c := &Challenge{d: d, x: NewInt(0), p: p}
+	for {
+		if _, err := rand.Read(b); err != nil {
+			panic(err)
+		}
+		if size == n {
+			// clear the bits above the modulus so that redraws are rare
+			b[0] &= byte(1<<(p.Modulus.BitLen()-8*(n-1)) - 1)
+		}
+		c.x.SetBytes(b)
+		if c.x.Cmp(p.Modulus) < 0 && !c.IsWeak() {
+			return c, nil
+		}
+	}
+}
+
+func (p *Params) format() wire.Format {
+	return wire.Format{
+		Version:       p.Version,
+		MaxValueBytes: (p.Modulus.BitLen() + 7) / 8,
+	}
+}
+
+func (c *Challenge) params() *Params {
+	if c.p == nil {
+		return ParamsP1279
+	}
+	return c.p
+}
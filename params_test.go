@@ -0,0 +1,170 @@
+package pow
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParamsPresets(t *testing.T) {
+	for _, tc := range []struct {
+		p    *Params
+		bits int
+	}{
+		{ParamsP1279, 1279},
+		{ParamsP2203, 2203},
+	} {
+		t.Run(tc.p.Version, func(t *testing.T) {
+			if tc.p.Modulus.BitLen() != tc.bits || !tc.p.Modulus.ProbablyPrime(20) {
+				t.Fatalf("modulus is not a %d-bit prime", tc.bits)
+			}
+			// the exponent must be (p+1)/4 = 2^(bits-2)
+			e := NewInt(0).Add(tc.p.Modulus, one)
+			e.Rsh(e, 2)
+			if e.Cmp(tc.p.Exponent) != 0 {
+				t.Fatalf("exponent is not (p+1)/4")
+			}
+
+			c := tc.p.GenerateChallenge(5)
+			s := c.String()
+			if !strings.HasPrefix(s, tc.p.Version+".") {
+				t.Errorf("String() = %s, want prefix %s.", s, tc.p.Version)
+			}
+			decoded, err := DecodeChallenge(s)
+			if err != nil {
+				t.Fatalf("DecodeChallenge(%s): %v", s, err)
+			}
+			if decoded.params() != tc.p || decoded.d != c.d || decoded.x.Cmp(c.x) != 0 {
+				t.Errorf("DecodeChallenge(%s) did not round-trip", s)
+			}
+			solution := c.Solve()
+			if !strings.HasPrefix(solution, tc.p.Version+".") {
+				t.Errorf("Solve() = %s, want prefix %s.", solution, tc.p.Version)
+			}
+			if good, err := decoded.Check(solution); err != nil || !good {
+				t.Errorf("Check = %v, %v; want true, nil", good, err)
+			}
+		})
+	}
+}
+
+func TestParamsMismatch(t *testing.T) {
+	c := ParamsP2203.GenerateChallenge(2)
+	other := &Challenge{d: c.d, x: c.x}
+	if _, err := c.Check(other.Solve()); err == nil {
+		t.Error("Check accepted a solution in the wrong version")
+	}
+	if _, err := DecodeChallenge("s1.AAAAAg==.AA=="); err == nil {
+		t.Error("DecodeChallenge accepted an unknown version")
+	}
+}
+
+func TestWorkUnits(t *testing.T) {
+	for _, tc := range []struct {
+		c    *Challenge
+		want uint64
+	}{
+		{&Challenge{d: 90000, x: NewInt(12345)}, 90000 * 1277},
+		{&Challenge{d: 10, x: NewInt(12345), p: ParamsP2203}, 10 * 2201},
+		{&Challenge{d: 0, x: NewInt(12345)}, 0},
+		{&Challenge{d: 1<<32 - 1, x: NewInt(2)}, (1<<32 - 1) * 1277},
+		{&Challenge{d: 90000, x: NewInt(0)}, 0},
+		{&Challenge{d: 90000, x: NewInt(1)}, 0},
+	} {
+		if got := tc.c.WorkUnits(); got != tc.want {
+			t.Errorf("WorkUnits() for %s = %d, want %d", tc.c, got, tc.want)
+		}
+	}
+}
+
+func TestNewMersenneParams(t *testing.T) {
+	p, err := NewMersenneParams("s607", 607)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewChallengeWithParams(p, 5, []byte{0x12, 0x34, 0x56})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := c.String(); !strings.HasPrefix(s, "s607.") {
+		t.Errorf("String() = %s, want prefix s607.", s)
+	}
+	if good, err := c.Check(c.Solve()); err != nil || !good {
+		t.Errorf("Check = %v, %v; want true, nil", good, err)
+	}
+	if _, err := NewChallengeWithParams(p, 5, p.Modulus.Bytes()); !errors.Is(err, ErrValueOutOfRange) {
+		t.Errorf("NewChallengeWithParams with the modulus: err = %v, want %v", err, ErrValueOutOfRange)
+	}
+
+	for _, tc := range []struct {
+		version string
+		n       uint
+	}{
+		{"s1", 1},
+		{"s11", 11}, // 2047 = 23 * 89
+		{"", 607},
+		{"s.607", 607},
+	} {
+		if _, err := NewMersenneParams(tc.version, tc.n); !errors.Is(err, ErrInvalidParams) {
+			t.Errorf("NewMersenneParams(%q, %d): err = %v, want %v", tc.version, tc.n, err, ErrInvalidParams)
+		}
+	}
+	bad := &Params{Modulus: ParamsP1279.Modulus, Exponent: two, Version: "bad"}
+	if _, err := NewChallengeWithParams(bad, 1, []byte{2}); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("NewChallengeWithParams with a wrong exponent: err = %v, want %v", err, ErrInvalidParams)
+	}
+	for _, p := range []*Params{ParamsP1279, ParamsP2203} {
+		if err := p.Validate(); err != nil {
+			t.Errorf("%s.Validate() = %v", p.Version, err)
+		}
+	}
+}
+
+func TestGenerateChallengeSmallModulus(t *testing.T) {
+	for _, n := range []uint{3, 7, 31, 89, 127} {
+		p, err := NewMersenneParams(fmt.Sprintf("s%d", n), n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 200; i++ {
+			if c := p.GenerateChallenge(3); c.Valid() != nil {
+				t.Fatalf("2^%d-1: GenerateChallenge(3) = %s, Valid() = %v", n, c, c.Valid())
+			}
+		}
+	}
+	three := &Params{Modulus: NewInt(3), Exponent: one, Version: "t3"}
+	if c := three.GenerateChallenge(1); c.value().Cmp(three.Modulus) >= 0 {
+		t.Errorf("GenerateChallenge modulo 3 = %s, not in the field", c)
+	}
+}
+
+func TestGenerateChallengeSize(t *testing.T) {
+	for _, tc := range []struct {
+		p    *Params
+		size int
+	}{
+		{ParamsP1279, 1},
+		{ParamsP1279, 32},
+		{ParamsP1279, 160},
+		{ParamsP2203, 276},
+	} {
+		for i := 0; i < 20; i++ {
+			c, err := tc.p.GenerateChallengeSize(7, tc.size)
+			if err != nil {
+				t.Fatalf("%s.GenerateChallengeSize(7, %d): %v", tc.p.Version, tc.size, err)
+			}
+			if len(c.ValueBytes()) > tc.size || c.Valid() != nil {
+				t.Fatalf("%s.GenerateChallengeSize(7, %d) = %s, Valid() = %v", tc.p.Version, tc.size, c, c.Valid())
+			}
+			if _, err := DecodeChallenge(c.String()); err != nil {
+				t.Errorf("DecodeChallenge(%s): %v", c, err)
+			}
+		}
+	}
+	for _, size := range []int{0, -1, 161} {
+		if _, err := GenerateChallengeSize(1, size); !errors.Is(err, ErrValueOutOfRange) {
+			t.Errorf("GenerateChallengeSize(1, %d): err = %v, want %v", size, err, ErrValueOutOfRange)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package pow
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// A ClientPolicy chooses difficulties per client, starting every client at
+// a base difficulty and doubling it for each failed or missing proof, with
+// the penalty halving every HalfLife. Clients are identified by a string key
+// chosen by the front end, such as an IP address, a prefix of one, or an API
+// token, so an HTTP middleware and a TCP proxy can share one policy. It is
+// safe for concurrent use.
+type ClientPolicy struct {
+	// HalfLife is how long it takes for a client's penalty to halve. The
+	// default is 10 minutes.
+	HalfLife time.Duration
+	// Base, if not nil, gives the difficulty of clients without a
+	// penalty in place of min, for instance DifficultyController.Difficulty.
+	Base func() uint32
+
+	min, max uint32
+
+	mu      sync.Mutex
+	clients lru // values are *clientPenalty
+}
+
+// clientPenalty is the number of doublings a client has earned, as of
+// updated.
+type clientPenalty struct {
+	level   float64
+	updated time.Time
+}
+
+// NewClientPolicy returns a ClientPolicy issuing difficulties between min
+// and max and tracking up to size clients, forgetting the least recently
+// seen beyond that.
+func NewClientPolicy(min, max uint32, size int) *ClientPolicy {
+	if max < min {
+		max = min
+	}
+	return &ClientPolicy{min: min, max: max, clients: newLRU(size, 0)}
+}
+
+// Difficulty returns the difficulty to issue to the client key.
+func (cp *ClientPolicy) Difficulty(key string) uint32 {
+	base := cp.min
+	if cp.Base != nil {
+		base = cp.Base()
+	}
+	if base < 1 {
+		base = 1
+	}
+	cp.mu.Lock()
+	level := cp.level(key, now())
+	cp.mu.Unlock()
+	d := math.Round(float64(base) * math.Exp2(level))
+	if d > float64(cp.max) {
+		return cp.max
+	}
+	if d < float64(cp.min) {
+		return cp.min
+	}
+	return uint32(d)
+}
+
+// Failure records a failed proof from the client key, or a challenge it
+// was issued and never solved, doubling its difficulty.
+func (cp *ClientPolicy) Failure(key string) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	t := now()
+	level := cp.level(key, t)
+	// Doublings past the point where the base reaches max only lengthen
+	// the time it takes to come back down.
+	if limit := math.Log2(float64(cp.max) / math.Max(float64(cp.min), 1)); level+1 > limit {
+		level = math.Max(limit, level)
+	} else {
+		level++
+	}
+	cp.clients.add(key, &clientPenalty{level: level, updated: t})
+}
+
+// Forgive clears the penalty of the client key, for instance after it
+// authenticates.
+func (cp *ClientPolicy) Forgive(key string) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.clients.add(key, &clientPenalty{updated: now()})
+}
+
+// level returns the decayed penalty of key at t. cp.mu must be held.
+func (cp *ClientPolicy) level(key string, t time.Time) float64 {
+	v, ok := cp.clients.get(key)
+	if !ok {
+		return 0
+	}
+	p := v.(*clientPenalty)
+	halfLife := cp.HalfLife
+	if halfLife <= 0 {
+		halfLife = 10 * time.Minute
+	}
+	return p.level * math.Exp2(-float64(t.Sub(p.updated))/float64(halfLife))
+}
@@ -0,0 +1,58 @@
+package pow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientPolicy(t *testing.T) {
+	defer func() { now = time.Now }()
+	clock := time.Unix(1700000000, 0)
+	now = func() time.Time { return clock }
+
+	cp := NewClientPolicy(1000, 10000, 100)
+	cp.HalfLife = time.Minute
+	if d := cp.Difficulty("10.0.0.1"); d != 1000 {
+		t.Errorf("new client Difficulty = %d, want 1000", d)
+	}
+
+	// Failures double the difficulty, up to max, for that client only
+	for _, want := range []uint32{2000, 4000, 8000, 10000, 10000} {
+		cp.Failure("10.0.0.1")
+		if d := cp.Difficulty("10.0.0.1"); d != want {
+			t.Errorf("Difficulty after failures = %d, want %d", d, want)
+		}
+	}
+	if d := cp.Difficulty("10.0.0.2"); d != 1000 {
+		t.Errorf("other client Difficulty = %d, want 1000", d)
+	}
+
+	// The penalty halves every HalfLife, and the excess failures beyond
+	// max do not make it last longer
+	for _, want := range []uint32{3162, 1778, 1333} {
+		clock = clock.Add(time.Minute)
+		if d := cp.Difficulty("10.0.0.1"); d < want-1 || d > want+1 {
+			t.Errorf("Difficulty while decaying = %d, want %d", d, want)
+		}
+	}
+
+	cp.Forgive("10.0.0.1")
+	if d := cp.Difficulty("10.0.0.1"); d != 1000 {
+		t.Errorf("Difficulty after Forgive = %d, want 1000", d)
+	}
+}
+
+func TestClientPolicyBase(t *testing.T) {
+	cp := NewClientPolicy(100, 100000, 1)
+	base := uint32(500)
+	cp.Base = func() uint32 { return base }
+	cp.Failure("a")
+	if d := cp.Difficulty("a"); d != 1000 {
+		t.Errorf("Difficulty = %d, want twice the base", d)
+	}
+	// Only size clients are tracked
+	cp.Failure("b")
+	if d := cp.Difficulty("a"); d != 500 {
+		t.Errorf("Difficulty of evicted client = %d, want the base", d)
+	}
+}
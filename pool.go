@@ -0,0 +1,20 @@
+package pow
+
+import "sync"
+
+// intPool holds scratch Ints for Solve and Check. A busy verifier reuses
+// their storage instead of allocating it on every call, which with gmp also
+// means C allocations and finalizers.
+var intPool = sync.Pool{
+	New: func() interface{} { return NewInt(0) },
+}
+
+// getInt returns a scratch Int with an unspecified value.
+func getInt() *Int {
+	return intPool.Get().(*Int)
+}
+
+// putInt returns x to the pool. x must not be used afterwards.
+func putInt(x *Int) {
+	intPool.Put(x)
+}
@@ -0,0 +1,38 @@
+package pow
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCheckConcurrent(t *testing.T) {
+	c := GenerateChallenge(20)
+	good := c.Solve()
+	bad := GenerateChallenge(20).Solve()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if ok, err := c.Check(good); !ok || err != nil {
+					t.Errorf("Check(good) = %v, %v", ok, err)
+				}
+				if ok, _ := c.Check(bad); ok {
+					t.Error("Check(bad) = true")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkCheck(b *testing.B) {
+	c := GenerateChallenge(100)
+	s := c.Solve()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Check(s)
+	}
+}
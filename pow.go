@@ -1,179 +1,906 @@
 package pow
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
-	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
+	"runtime/pprof"
 	"strings"
+	"time"
 
-	"github.com/ncw/gmp"
+	"github.com/redpwn/pow/internal/wire"
 )
 
 const version = "s"
 
 var (
-	mod = gmp.NewInt(0)
-	exp = gmp.NewInt(0)
-	one = gmp.NewInt(1)
-	two = gmp.NewInt(2)
+	mod  = ParamsP1279.Modulus
+	exp  = ParamsP1279.Exponent
+	zero = NewInt(0)
+	one  = NewInt(1)
+	two  = NewInt(2)
 )
 
-func init() {
-	mod.Lsh(one, 1279)
-	mod.Sub(mod, one)
-	exp.Lsh(one, 1277)
-}
+// MaxCheckDifficulty is the largest difficulty Check will verify and
+// DecodeChallenge and the other decoders will accept. Check performs one
+// modular squaring per unit of difficulty, and Solve a full exponentiation,
+// so without a cap a challenge with a difficulty near the uint32 maximum
+// would tie up the verifier for hours and the solver for years. The default
+// of 1<<20 is far beyond what any client can solve in reasonable time; raise
+// it if you legitimately issue larger difficulties, or use
+// DecodeChallengeLimit and CheckLimit to choose a limit per call.
+var MaxCheckDifficulty uint32 = 1 << 20
+
+// ErrDifficultyTooHigh is returned by Check and the decoders for challenges
+// whose difficulty exceeds MaxCheckDifficulty or the limit passed in.
+var ErrDifficultyTooHigh = errors.New("difficulty too high")
+
+// RejectWeakChallenges makes Check return ErrWeakChallenge for challenges
+// that can be solved without doing the work (see IsWeak). Enable it when
+// verifying challenges from an issuer you do not fully trust.
+var RejectWeakChallenges = false
+
+// ErrWeakChallenge is returned by Check for weak challenges when
+// RejectWeakChallenges is set.
+var ErrWeakChallenge = errors.New("weak challenge")
+
+// RequireCanonicalSolutions makes Check return ErrNonCanonical for solutions
+// not written exactly as Solve writes them, apart from surrounding
+// whitespace. By default Check also accepts the zero padding kCTF clients
+// emit, unpadded and URL-safe base64. Enable it when solutions are keyed by
+// their string, as in a replay cache, and all clients use this package, or
+// key by CanonicalSolution instead.
+//
+// Even canonical solutions are not unique: Check also accepts the twin
+// solutions described at RejectTwinSolutions.
+var RequireCanonicalSolutions = false
+
+// RejectTwinSolutions makes Check accept only the solution Solve returns.
+// Check squares away the sign of each value, so for every solution y it
+// also accepts its twin, the negation p-y. By default both are accepted,
+// which is what redpwnpow and kCTF verifiers do. Set it when integrators
+// expect a single valid proof value, for instance to key solutions by value.
+//
+// The solution Solve returns is y = r XOR 1 with r = u^e for the previous
+// value u and e = (p+1)/4, while its twin has r negated. When p is 7 mod 8,
+// as every Mersenne prime including the presets is, e is even, so r is a
+// square and -r is not, and telling them apart is a single Jacobi symbol
+// rather than a second solve. When p is 3 mod 8, e is odd and r is a square
+// only if u is, which Check cannot learn without redoing the work, so with
+// RejectTwinSolutions set Check returns ErrInvalidParams for such fields and
+// Params.Validate rejects them. The smaller of y and p-y is not necessarily
+// the one Solve returns, so requiring it instead would reject half of all
+// honest solutions.
+var RejectTwinSolutions = false
+
+// ErrNonCanonical is returned by Check for solutions that are not in
+// canonical form when RequireCanonicalSolutions is set. It matches
+// ErrBadEncoding.
+var ErrNonCanonical = fmt.Errorf("%w: solution not in canonical form", ErrBadEncoding)
+
+// RequireCanonicalChallenges makes DecodeChallenge and the other challenge
+// decoders (Params.Decode, DecodeChallengeHex, DecodeCompact and
+// UnmarshalBinary) return ErrNonCanonicalChallenge for challenges not written
+// exactly as the matching encoder writes them, apart from surrounding
+// whitespace in the text forms. By default shorter difficulty fields, leading
+// zeros in the value, and unpadded and URL-safe base64 are accepted, so many
+// strings decode to the same challenge. Enable it when challenge strings are
+// used as keys and all issuers use this package, or key by the String of the
+// decoded challenge instead.
+var RequireCanonicalChallenges = false
+
+// ErrNonCanonicalChallenge is returned by the decoders for challenges that
+// are not in canonical form when RequireCanonicalChallenges is set. It
+// matches ErrBadEncoding.
+var ErrNonCanonicalChallenge = fmt.Errorf("%w: challenge not in canonical form", ErrBadEncoding)
+
+// Errors from decoding challenges and solutions wrap one of these, so that
+// callers can tell them apart with errors.Is.
+var (
+	// ErrBadVersion means the version prefix is missing or unknown.
+	ErrBadVersion = wire.ErrBadVersion
+	// ErrBadDifficulty means the difficulty does not fit in 32 bits.
+	ErrBadDifficulty = wire.ErrBadDifficulty
+	// ErrBadEncoding means the input is not well-formed, for example
+	// because of invalid base64 or the wrong number of segments.
+	ErrBadEncoding = wire.ErrBadEncoding
+	// ErrValueOutOfRange means a value is not in the field. Valid also
+	// returns it for challenges built with such a value.
+	ErrValueOutOfRange = wire.ErrValueOutOfRange
+)
+
+// ErrUnsupportedVersion is returned by Check for solutions whose version
+// prefix belongs to no registered scheme, as opposed to a known version that
+// does not fit the challenge. It matches ErrBadVersion.
+var ErrUnsupportedVersion = fmt.Errorf("%w: no scheme registered", ErrBadVersion)
+
+// ErrValueTooLarge is returned by the decoders and Check for challenges and
+// solutions whose value is not smaller than the modulus. It matches
+// ErrValueOutOfRange.
+var ErrValueTooLarge = fmt.Errorf("%w: value not smaller than modulus", ErrValueOutOfRange)
+
+// MinDifficulty is the smallest difficulty GenerateChallengeChecked will
+// issue. It must be at least 1: a challenge of difficulty 0 is solved by
+// echoing its value back.
+var MinDifficulty uint32 = 1
+
+// ErrDifficultyTooLow is returned by GenerateChallengeChecked for
+// difficulties below MinDifficulty.
+var ErrDifficultyTooLow = errors.New("difficulty below MinDifficulty")
+
+// ErrDeadlineExceeded is wrapped in the error SolveDeadline returns when the
+// timeout elapses before the solve completes.
+var ErrDeadlineExceeded = errors.New("solve deadline exceeded")
 
 type Challenge struct {
 	d uint32
-	x *gmp.Int
+	x *Int    // nil means 0
+	p *Params // nil means ParamsP1279
+}
+
+// value returns the starting value of the challenge.
+func (c *Challenge) value() *Int {
+	if c.x == nil {
+		return zero
+	}
+	return c.x
+}
+
+// ValueBytes returns the big-endian bytes of the challenge value, with no
+// leading zeros.
+func (c *Challenge) ValueBytes() []byte {
+	return c.value().Bytes()
 }
 
-// DecodeChallenge decodes a redpwnpow challenge produced by String.
+// ValueHex returns the challenge value in lowercase hexadecimal without
+// leading zeros, for logging and display.
+func (c *Challenge) ValueHex() string {
+	return fmt.Sprintf("%x", c.value())
+}
+
+// Valid reports why the challenge cannot provide a proof of work, or nil if
+// it can. It returns ErrValueOutOfRange if x is not smaller than the modulus
+// and ErrWeakChallenge if x is unset or weak (see IsWeak).
+func (c *Challenge) Valid() error {
+	switch {
+	case c.x == nil:
+		return fmt.Errorf("%w: value not set", ErrWeakChallenge)
+	case c.x.Sign() < 0 || c.x.Cmp(c.params().Modulus) >= 0:
+		return ErrValueOutOfRange
+	case c.IsWeak():
+		return ErrWeakChallenge
+	}
+	return nil
+}
+
+// DecodeChallenge decodes a redpwnpow challenge produced by String. The
+// version prefix selects the parameters the challenge uses, among the presets
+// and those registered with RegisterScheme; use DecodePuzzle for other
+// schemes. Standard and URL-safe base64 are both accepted, with or without
+// padding.
 func DecodeChallenge(v string) (*Challenge, error) {
-	parts := strings.SplitN(v, ".", 3)
-	if len(parts) != 3 || parts[0] != version {
-		return nil, errors.New("incorrect version")
+	return DecodeChallengeLimit(v, MaxCheckDifficulty)
+}
+
+// DecodeChallengeLimit is like DecodeChallenge but returns
+// ErrDifficultyTooHigh for challenges whose difficulty exceeds max instead of
+// MaxCheckDifficulty.
+func DecodeChallengeLimit(v string, max uint32) (*Challenge, error) {
+	c, _, err := decodeChallengeDetailed(v, max)
+	return c, err
+}
+
+// ChallengeParts holds the components of an encoded challenge as sent. The
+// decoded bytes keep any leading zero padding, such as kCTF's.
+type ChallengeParts struct {
+	Version         string
+	Difficulty      string // base64
+	Value           string // base64
+	DifficultyBytes []byte
+	ValueBytes      []byte
+}
+
+// DecodeChallengeDetailed is like DecodeChallenge but also returns the raw
+// components of the encoding, for diagnostic tools that would otherwise have
+// to parse it again.
+func DecodeChallengeDetailed(v string) (*Challenge, *ChallengeParts, error) {
+	return decodeChallengeDetailed(v, MaxCheckDifficulty)
+}
+
+func decodeChallengeDetailed(v string, max uint32) (*Challenge, *ChallengeParts, error) {
+	p, err := lookupParams(v)
+	if err != nil {
+		return nil, nil, err
 	}
-	dBytes, err := base64.StdEncoding.DecodeString(parts[1])
+	d, xBytes, parts, err := p.format().ParseChallengeParts(v)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if len(dBytes) > 4 {
-		return nil, errors.New("difficulty too long")
+	c, err := p.newChallenge(d, xBytes, max)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := requireCanonical(v, c.String()); err != nil {
+		return nil, nil, err
+	}
+	return c, (*ChallengeParts)(&parts), nil
+}
+
+// requireCanonical returns ErrNonCanonicalChallenge if
+// RequireCanonicalChallenges is set and v, apart from surrounding whitespace,
+// differs from canonical.
+func requireCanonical(v, canonical string) error {
+	if RequireCanonicalChallenges && strings.TrimSpace(v) != canonical {
+		return ErrNonCanonicalChallenge
+	}
+	return nil
+}
+
+// newChallenge returns a challenge of difficulty d with the value given by the
+// big-endian bytes x. It returns ErrDifficultyTooHigh if d exceeds max, and
+// ErrValueTooLarge if x is not in the field: values outside the field would
+// be reduced by the first iteration, so they would encode a different
+// challenge from the one that is solved.
+func (p *Params) newChallenge(d uint32, x []byte, max uint32) (*Challenge, error) {
+	if d > max {
+		return nil, ErrDifficultyTooHigh
+	}
+	v := NewInt(0).SetBytes(x)
+	if v.Cmp(p.Modulus) >= 0 {
+		return nil, ErrValueTooLarge
 	}
-	// pad start with 0s to 4 bytes
-	dBytes = append(make([]byte, 4-len(dBytes)), dBytes...)
-	xBytes, err := base64.StdEncoding.DecodeString(parts[2])
+	return &Challenge{d: d, x: v, p: p}, nil
+}
+
+// ParseDifficulty returns the difficulty of an encoded challenge without
+// decoding the rest of it.
+func ParseDifficulty(challenge string) (uint32, error) {
+	p, err := lookupParams(challenge)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	d := binary.BigEndian.Uint32(dBytes)
-	x := gmp.NewInt(0).SetBytes(xBytes)
-	return &Challenge{d: d, x: x}, nil
+	return p.format().ParseDifficulty(challenge)
+}
+
+// EncodeDifficulty returns the big-endian bytes String uses to encode the
+// difficulty d, before base64.
+func EncodeDifficulty(d uint32) []byte {
+	return wire.EncodeDifficulty(d)
+}
+
+// DecodeDifficulty decodes big-endian difficulty bytes as produced by
+// EncodeDifficulty. Shorter inputs and leading zero bytes are accepted.
+func DecodeDifficulty(b []byte) (uint32, error) {
+	return wire.DecodeDifficulty(b)
+}
+
+func lookupParams(v string) (*Params, error) {
+	p, ok := paramsForVersion(wire.Prefix(v))
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrBadVersion, wire.Prefix(v))
+	}
+	return p, nil
+}
+
+// Parameters returns copies of the modulus and exponent of the default field
+// ParamsP1279. Each iteration of a challenge maps x to
+// x^exponent XOR 1 mod modulus.
+func Parameters() (modulus, exponent *Int) {
+	return NewInt(0).Set(mod), NewInt(0).Set(exp)
 }
 
 // GenerateChallenge creates a new random challenge.
+//
+// A challenge of difficulty 0 requires no work, since its solution is its own
+// value. Use GenerateChallengeChecked when d is computed rather than fixed.
 func GenerateChallenge(d uint32) *Challenge {
 	b := make([]byte, 16)
 	if _, err := rand.Read(b); err != nil {
 		panic(err)
 	}
 	return &Challenge{
-		x: gmp.NewInt(0).SetBytes(b),
+		x: NewInt(0).SetBytes(b),
 		d: d,
 	}
 }
 
+// GenerateChallengeSize is like GenerateChallenge but draws a random value of
+// size bytes instead of 16; see Params.GenerateChallengeSize.
+func GenerateChallengeSize(d uint32, size int) (*Challenge, error) {
+	return ParamsP1279.GenerateChallengeSize(d, size)
+}
+
+// GenerateChallengeChecked is like GenerateChallenge but returns
+// ErrDifficultyTooLow if d is below MinDifficulty, or 0 if MinDifficulty is
+// unset.
+func GenerateChallengeChecked(d uint32) (*Challenge, error) {
+	if d == 0 || d < MinDifficulty {
+		return nil, ErrDifficultyTooLow
+	}
+	return GenerateChallenge(d), nil
+}
+
+// NewChallenge returns a challenge of difficulty d whose value has the
+// big-endian bytes x, for deployments and tests that need deterministic
+// challenges. It returns ErrValueTooLarge, which matches ErrValueOutOfRange,
+// unless x is smaller than the modulus. Weak values are accepted; see Valid.
+func NewChallenge(d uint32, x []byte) (*Challenge, error) {
+	return ParamsP1279.newChallenge(d, x, math.MaxUint32)
+}
+
+// Clone returns a deep copy of c that can be modified independently.
+func (c *Challenge) Clone() *Challenge {
+	return &Challenge{d: c.d, x: NewInt(0).Set(c.value()), p: c.p}
+}
+
+// Reset sets c to a challenge of difficulty d with value x, reusing the
+// existing storage for the value. x is copied, not retained. The parameters
+// of c are left unchanged. Like NewChallenge, it returns ErrValueTooLarge
+// unless x is smaller than the modulus, and ErrValueOutOfRange if x is
+// negative; c is not modified then.
+func (c *Challenge) Reset(d uint32, x *Int) error {
+	if x.Sign() < 0 {
+		return ErrValueOutOfRange
+	}
+	if x.Cmp(c.params().Modulus) >= 0 {
+		return ErrValueTooLarge
+	}
+	c.d = d
+	if c.x == nil {
+		c.x = NewInt(0)
+	}
+	c.x.Set(x)
+	return nil
+}
+
+// IsWeak reports whether the challenge is trivially solvable regardless of
+// its difficulty. This is the case when x is 0, 1, or -1 modulo the field:
+// 0 and 1 alternate under each iteration, and -1 maps straight to 0.
+func (c *Challenge) IsWeak() bool {
+	m := c.params().Modulus
+	x := getInt()
+	defer putInt(x)
+	x.Mod(c.value(), m)
+	if x.Cmp(one) <= 0 {
+		return true
+	}
+	return x.Add(x, one).Cmp(m) == 0
+}
+
+// WorkUnits returns the number of modular squarings a full Solve performs,
+// as a hardware-independent measure of the cost of the challenge. Each
+// iteration raises x to a power of two, which takes one squaring per bit
+// after the first. Challenges that Solve short-circuits (x of 0 or 1) cost
+// nothing.
+func (c *Challenge) WorkUnits() uint64 {
+	if x := c.value(); x.Sign() == 0 || x.Cmp(one) == 0 {
+		return 0
+	}
+	return uint64(c.d) * uint64(c.params().Exponent.BitLen()-1)
+}
+
 // String encodes the challenge in a format that can be decoded by DecodeChallenge.
+//
+// The encoding is canonical, so equal challenges always encode to the same
+// string: the version prefix, the difficulty as exactly four big-endian
+// bytes, and the value as its minimal big-endian bytes (no leading zeros,
+// and no bytes at all for zero), each byte field in padded standard base64.
+// DecodeChallenge also accepts non-canonical forms such as kCTF's
+// zero-padded numbers; re-encoding them yields the canonical form.
 func (c *Challenge) String() string {
-	b := make([]byte, 4)
-	binary.BigEndian.PutUint32(b, c.d)
-	return fmt.Sprintf("%s.%s.%s", version, base64.StdEncoding.EncodeToString(b), base64.StdEncoding.EncodeToString(c.x.Bytes()))
+	b := wire.EncodeDifficulty(c.d)
+	return fmt.Sprintf("%s.%s.%s", c.params().Version, base64.StdEncoding.EncodeToString(b), base64.StdEncoding.EncodeToString(c.value().Bytes()))
+}
+
+// StringURL is like String but uses unpadded URL-safe base64, so that the
+// challenge can be placed in a query string without escaping. It can be
+// decoded by DecodeChallengeURL.
+func (c *Challenge) StringURL() string {
+	return c.params().format().EncodeURL(c.d, c.value().Bytes())
+}
+
+// DecodeChallengeURL decodes a challenge produced by StringURL. It is
+// equivalent to DecodeChallenge, which accepts both encodings.
+func DecodeChallengeURL(v string) (*Challenge, error) {
+	return DecodeChallenge(v)
 }
 
 // Solve solves the challenge and returns a solution proof that can be checked by Check.
+//
+// Solution values are encoded as their minimal big-endian bytes in padded
+// base64, so a solution of zero has an empty value, as in "s.". The solutions
+// for the edge cases 0 and 1 are encoded once per Params and reused.
 func (c *Challenge) Solve() string {
-	x := gmp.NewInt(0).Set(c.x) // dont mutate c.x
-	
-	// Fast path for edge cases (though rare in practice)
-	if x.Sign() == 0 {
-		// 0 -> 1 -> 0 -> 1 ... alternating pattern
-		if c.d%2 == 0 {
-			// Even number of iterations: 0 -> 1 -> 0 -> ... -> 0
-			return fmt.Sprintf("%s.%s", version, base64.StdEncoding.EncodeToString(gmp.NewInt(0).Bytes()))
-		} else {
-			// Odd number of iterations: 0 -> 1 -> 0 -> ... -> 1
-			return fmt.Sprintf("%s.%s", version, base64.StdEncoding.EncodeToString(one.Bytes()))
-		}
+	s, _ := c.SolveContext(context.Background())
+	return s
+}
+
+// SolveContext is like Solve but stops between iterations once ctx is done,
+// returning an *IncompleteError that wraps ctx.Err().
+func (c *Challenge) SolveContext(ctx context.Context) (string, error) {
+	x := getInt()
+	defer putInt(x)
+	x, n, err := c.solveTimed(ctx, x)
+	if err != nil {
+		return "", c.incomplete(err, n, x)
 	}
+	return c.params().encodeSolution(x), nil
+}
+
+// IncompleteError is returned by solves that stop before the last iteration.
+// It records how far the solve got, so that the caller can resume it, log
+// it, or give partial credit.
+type IncompleteError struct {
+	Err        error  // why the solve stopped, such as ctx.Err()
+	Iterations uint32 // iterations completed
+	Difficulty uint32 // iterations in the full solve
+	// State is the progress in the form of Solver.State. Passing it to
+	// Resume on a Solver for the same challenge continues the solve.
+	State []byte
+}
+
+func (e *IncompleteError) Error() string {
+	return fmt.Sprintf("%v after %d of %d iterations", e.Err, e.Iterations, e.Difficulty)
+}
+
+// Unwrap returns e.Err.
+func (e *IncompleteError) Unwrap() error {
+	return e.Err
+}
+
+func (c *Challenge) incomplete(err error, n uint32, x *Int) *IncompleteError {
+	return &IncompleteError{Err: err, Iterations: n, Difficulty: c.d, State: c.state(n, x)}
+}
+
+// SolveDeadline is like Solve but gives up once timeout has elapsed, without
+// leaving any computation running. It returns the number of iterations
+// performed either way; if the solve did not finish in time the solution is
+// empty and the error is an *IncompleteError wrapping ErrDeadlineExceeded.
+func (c *Challenge) SolveDeadline(timeout time.Duration) (string, uint32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	x := getInt()
+	defer putInt(x)
+	x, n, err := c.solveTimed(ctx, x)
+	if err != nil {
+		return "", n, c.incomplete(ErrDeadlineExceeded, n, x)
+	}
+	return c.params().encodeSolution(x), n, nil
+}
+
+// AppendSolution is like Solve but appends the solution to dst and returns
+// the extended buffer. Batch solvers can reuse dst across calls to avoid
+// allocating a string per solution.
+func (c *Challenge) AppendSolution(dst []byte) []byte {
+	x := getInt()
+	defer putInt(x)
+	x, _, _ = c.solveTimed(context.Background(), x)
+	return c.params().appendSolution(dst, x)
+}
+
+// encodeBufSize is the size of the stack buffers used while encoding, enough
+// for values up to 4096 bits. Larger values spill to the heap.
+const encodeBufSize = 512
+
+// encodeSolution encodes the solution x. Values are written as their minimal
+// big-endian bytes, so the canonical encoding of zero has an empty value, as
+// in "s.". Check also accepts zero-padded forms such as kCTF's "s.AAAA".
+func (p *Params) encodeSolution(x *Int) string {
+	if p.edges[0] != "" && x.BitLen() <= 1 {
+		return p.edges[x.Bit(0)]
+	}
+	var buf [encodeBufSize]byte
+	return string(p.appendSolution(buf[:0], x))
+}
+
+// appendSolution appends the encoded solution x to dst. With the math/big
+// backend it does not allocate unless dst must grow.
+func (p *Params) appendSolution(dst []byte, x *Int) []byte {
+	if hook := PhaseHook; hook != nil {
+		defer reportPhase(hook, PhaseEncode, time.Now())
+	}
+	var vb [encodeBufSize]byte
+	v := appendValueBytes(vb[:0], x)
+	dst = append(dst, p.Version...)
+	dst = append(dst, '.')
+	n := len(dst)
+	dst = append(dst, make([]byte, base64.StdEncoding.EncodedLen(len(v)))...)
+	base64.StdEncoding.Encode(dst[n:], v)
+	return dst
+}
+
+// solveTimed wraps solve, reporting the run to SolveHook and PhaseHook and
+// applying the profiling labels.
+func (c *Challenge) solveTimed(ctx context.Context, x *Int) (*Int, uint32, error) {
+	if ProfileLabels {
+		defer pprof.SetGoroutineLabels(c.setLabels(ctx, "solve"))
+	}
+	start := time.Now()
+	x, n, err := c.solve(ctx, x)
+	if hook := SolveHook; hook != nil {
+		hook(SolveStats{Difficulty: c.d, Iterations: n, Elapsed: time.Since(start)})
+	}
+	if hook := PhaseHook; hook != nil {
+		reportPhase(hook, PhaseIterate, start)
+	}
+	return x, n, err
+}
+
+// solveBlock is the number of iterations solve runs between cancellation
+// checks. Each iteration is a full modular exponentiation, so
+// BenchmarkSolveBlock shows no measurable difference between block sizes of
+// 1 and 64: the per-block check is noise next to the arithmetic. 8 keeps
+// cancellation latency to a few milliseconds on typical hardware.
+var solveBlock uint32 = 8
+
+// solve runs the challenge iterations in x and returns it along with the
+// number of iterations performed. If ctx is done before the last iteration,
+// the partial value and count are returned with ctx.Err().
+//
+// The loop is strictly sequential, and speculating on the XOR does not help:
+// the XOR only flips the low bit of x^e, which is known as soon as x^e is,
+// while the next exponentiation depends on all of x^e. Both branches would
+// have to wait for the same exponentiation, after which the choice is free.
+func (c *Challenge) solve(ctx context.Context, x *Int) (*Int, uint32, error) {
+	x.Set(c.value()) // dont mutate c.x
 	
-	if x.Cmp(one) == 0 {
-		// 1 -> 0 -> 1 -> 0 ... alternating pattern
-		if c.d%2 == 0 {
-			// Even number of iterations: 1 -> 0 -> 1 -> ... -> 1
-			return fmt.Sprintf("%s.%s", version, base64.StdEncoding.EncodeToString(one.Bytes()))
-		} else {
-			// Odd number of iterations: 1 -> 0 -> 1 -> ... -> 0
-			return fmt.Sprintf("%s.%s", version, base64.StdEncoding.EncodeToString(gmp.NewInt(0).Bytes()))
+	// Fast path for edge cases (though rare in practice): 0 and 1 map to
+	// each other in the default fields, so their trajectories repeat
+	if x.Sign() == 0 || x.Cmp(one) == 0 {
+		if y, ok := c.params().shortCycle(x, c.d); ok {
+			return x.Set(y), c.d, nil
 		}
 	}
 	
-	// Optimization: Unroll loop for small difficulties to reduce loop overhead
-	if c.d <= 4 {
-		switch c.d {
-		case 1:
-			x.Exp(x, exp, mod)
-			x.Xor(x, one)
-		case 2:
-			x.Exp(x, exp, mod)
-			x.Xor(x, one)
-			x.Exp(x, exp, mod)
-			x.Xor(x, one)
-		case 3:
-			x.Exp(x, exp, mod)
-			x.Xor(x, one)
-			x.Exp(x, exp, mod)
-			x.Xor(x, one)
-			x.Exp(x, exp, mod)
-			x.Xor(x, one)
-		case 4:
-			x.Exp(x, exp, mod)
-			x.Xor(x, one)
-			x.Exp(x, exp, mod)
-			x.Xor(x, one)
-			x.Exp(x, exp, mod)
-			x.Xor(x, one)
-			x.Exp(x, exp, mod)
-			x.Xor(x, one)
+	// Run the iterations in blocks, checking for cancellation between
+	// blocks. The last block holds whatever remains.
+	done := ctx.Done()
+	for i := uint32(0); i < c.d; {
+		select {
+		case <-done:
+			return x, i, ctx.Err()
+		default:
 		}
-	} else {
-		// General case: perform the computation
-		for i := uint32(0); i < c.d; i++ {
-			x.Exp(x, exp, mod)
-			x.Xor(x, one)
+		n := c.d - i
+		if n > solveBlock {
+			n = solveBlock
 		}
+		c.params().iterate(x, n)
+		i += n
 	}
-	
-	return fmt.Sprintf("%s.%s", version, base64.StdEncoding.EncodeToString(x.Bytes()))
+	return x, c.d, nil
 }
 
-func decodeSolution(s string) (*gmp.Int, error) {
-	parts := strings.SplitN(s, ".", 2)
-	if len(parts) != 2 || parts[0] != version {
-		return nil, errors.New("incorrect version")
+// iterate runs n challenge iterations on x in place, with the kernel chosen
+// by Backend for the default field.
+func (p *Params) iterate(x *Int, n uint32) {
+	if p.Modulus.Cmp(ParamsP1279.Modulus) == 0 {
+		selectKernel().iterate(x, n)
+		return
 	}
-	yBytes, err := base64.StdEncoding.DecodeString(parts[1])
-	if err != nil {
+	p.iterateExp(x, n)
+}
+
+// iterateExp is iterate with the backend's generic Exp.
+func (p *Params) iterateExp(x *Int, n uint32) {
+	for ; n > 0; n-- {
+		x.Exp(x, p.Exponent, p.Modulus)
+		x.Xor(x, one)
+	}
+}
+
+// shortCycle returns the value after d iterations from x if x lies on a
+// cycle of length one or two, which it finds by running the two iterations.
+func (p *Params) shortCycle(x *Int, d uint32) (*Int, bool) {
+	y := NewInt(0).Exp(x, p.Exponent, p.Modulus)
+	y.Xor(y, one)
+	z := NewInt(0).Exp(y, p.Exponent, p.Modulus)
+	z.Xor(z, one)
+	if z.Cmp(x) != 0 {
+		return nil, false
+	}
+	if d%2 == 0 {
+		return z, true
+	}
+	return y, true
+}
+
+// ForEach runs the iterations of the challenge one by one, calling fn with
+// the number of iterations completed so far (from 1 to the difficulty) and
+// the value after that iteration. ForEach stops early if fn returns false.
+// Unlike Solve it always performs the real computation, even for x of 0 or 1.
+//
+// x is shared with the iteration and is only valid until fn returns; fn must
+// not modify it and must copy it with Set to keep it.
+func (c *Challenge) ForEach(fn func(i uint32, x *Int) bool) {
+	x := NewInt(0).Set(c.value())
+	for i := uint32(1); i <= c.d && i != 0; i++ {
+		c.params().iterate(x, 1)
+		if !fn(i, x) {
+			return
+		}
+	}
+}
+
+// decodeSolution decodes the value of a solution. Encodings longer than the
+// modulus allows are rejected before they are decoded, and values not smaller
+// than the modulus before any arithmetic is done on them.
+func (p *Params) decodeSolution(s string) (*Int, error) {
+	y := NewInt(0)
+	if err := p.decodeSolutionTo(y, s); err != nil {
 		return nil, err
 	}
-	return gmp.NewInt(0).SetBytes(yBytes), nil
+	return y, nil
+}
+
+// decodeSolutionTo is like decodeSolution but decodes into y.
+func (p *Params) decodeSolutionTo(y *Int, s string) error {
+	yBytes, err := p.format().ParseSolution(s)
+	if err != nil {
+		return err
+	}
+	y.SetBytes(yBytes)
+	if y.Cmp(p.Modulus) >= 0 {
+		return ErrValueTooLarge
+	}
+	return nil
 }
 
-// Check verifies that a solution proof from Solve is correct.
+// Check verifies that a solution proof from Solve is correct. It does not
+// authenticate the challenge or enforce the expiry of signed challenges;
+// use VerifySignedSolution for those.
 func (c *Challenge) Check(s string) (bool, error) {
-	y, err := decodeSolution(s)
+	return c.checkContext(context.Background(), s, MaxCheckDifficulty)
+}
+
+// CheckContext is like Check but stops between iterations once ctx is done,
+// returning ctx.Err(), so that a verifier handed an expensive challenge can
+// always give up on it.
+func (c *Challenge) CheckContext(ctx context.Context, s string) (bool, error) {
+	return c.checkContext(ctx, s, MaxCheckDifficulty)
+}
+
+// CheckLimit is like Check but returns ErrDifficultyTooHigh for challenges
+// whose difficulty exceeds max instead of MaxCheckDifficulty.
+func (c *Challenge) CheckLimit(s string, max uint32) (bool, error) {
+	return c.checkContext(context.Background(), s, max)
+}
+
+// checkContext wraps check, reporting the result to CheckHook.
+func (c *Challenge) checkContext(ctx context.Context, s string, max uint32) (bool, error) {
+	hook := CheckHook
+	if hook == nil {
+		return c.check(ctx, s, max)
+	}
+	start := time.Now()
+	ok, err := c.check(ctx, s, max)
+	hook(CheckStats{Context: ctx, Difficulty: c.d, OK: ok, Err: err, Reason: ReasonOf(ok, err), Elapsed: time.Since(start)})
+	return ok, err
+}
+
+func (c *Challenge) check(ctx context.Context, s string, max uint32) (bool, error) {
+	if c.d > max {
+		return false, ErrDifficultyTooHigh
+	}
+	if RejectWeakChallenges && c.IsWeak() {
+		return false, ErrWeakChallenge
+	}
+	if ProfileLabels {
+		defer pprof.SetGoroutineLabels(c.setLabels(ctx, "check"))
+	}
+	hook := PhaseHook
+	var start time.Time
+	if hook != nil {
+		start = time.Now()
+	}
+	if v := wire.Prefix(s); v != c.params().Version {
+		return c.checkOtherVersion(ctx, s, v, hook, start)
+	}
+	p := c.params()
+	y := getInt()
+	defer putInt(y)
+	err := p.decodeSolutionTo(y, s)
+	if err == nil && RequireCanonicalSolutions && strings.TrimSpace(s) != p.encodeSolution(y) {
+		err = ErrNonCanonical
+	}
+	if hook != nil {
+		reportPhase(hook, PhaseDecode, start)
+	}
 	if err != nil {
 		return false, fmt.Errorf("decode solution: %w", err)
 	}
 	
 	// Fast path for edge cases
 	if c.d == 0 {
-		return y.Cmp(c.x) == 0, nil
+		return p.equalAny(y, c.value()), nil
+	}
+	if RejectTwinSolutions {
+		if twin, err := p.isTwin(y); twin || err != nil {
+			return false, err
+		}
 	}
 	
-	// Apply the inverse transformation d times
-	for i := uint32(0); i < c.d; i++ {
+	if hook != nil {
+		defer reportPhase(hook, PhaseIterate, time.Now())
+	}
+	if err := p.unwindContext(ctx, y, c.d); err != nil {
+		return false, err
+	}
+	return p.equalUpToSign(y, c.value()), nil
+}
+
+// checkOtherVersion checks a solution whose version prefix v is not the
+// challenge's, reporting phases to hook as check does. A checkpoint proof
+// over the challenge's parameters is verified in full on a single goroutine,
+// so that it takes no more of a Verifier's capacity than a plain solution;
+// anything else is an error, ErrUnsupportedVersion if v is not registered
+// at all.
+func (c *Challenge) checkOtherVersion(ctx context.Context, s, v string, hook func(Phase, time.Duration), start time.Time) (bool, error) {
+	p := c.params()
+	if v == wire.CheckpointPrefix+p.Version {
+		proof, err := DecodeCheckpointProof(s)
+		if err == nil && RequireCanonicalSolutions && strings.TrimSpace(s) != proof.String() {
+			err = ErrNonCanonical
+		}
+		if hook != nil {
+			reportPhase(hook, PhaseDecode, start)
+		}
+		if err != nil {
+			return false, fmt.Errorf("decode solution: %w", err)
+		}
+		if RejectTwinSolutions && c.d > 0 {
+			if twin, err := p.isTwin(proof.values[len(proof.values)-1]); twin || err != nil {
+				return false, err
+			}
+		}
+		if hook != nil {
+			defer reportPhase(hook, PhaseIterate, time.Now())
+		}
+		return c.CheckCheckpointed(ctx, proof, 1)
+	}
+	if _, ok := LookupScheme(strings.TrimPrefix(v, wire.CheckpointPrefix)); ok {
+		return false, fmt.Errorf("decode solution: %w %q for challenge version %q", ErrBadVersion, v, p.Version)
+	}
+	return false, fmt.Errorf("decode solution: %w %q", ErrUnsupportedVersion, v)
+}
+
+// unwind applies the inverse transformation n times to y, squaring into
+// scratch and reducing back into y. The sign of the value before each step
+// is lost, so the result is the earlier value or its negation.
+func (p *Params) unwind(y *Int, n uint32) {
+	p.unwindContext(context.Background(), y, n)
+}
+
+// checkBlock is the number of inverse iterations unwindContext runs between
+// cancellation checks. Each is a single squaring, so blocks are larger than
+// solveBlock to keep the check out of the profile.
+const checkBlock = 256
+
+// unwindContext is like unwind but stops between blocks of iterations once
+// ctx is done, returning ctx.Err().
+func (p *Params) unwindContext(ctx context.Context, y *Int, n uint32) error {
+	t, q := getInt(), getInt()
+	defer putInt(t)
+	defer putInt(q)
+	done := ctx.Done()
+	for i := uint32(0); i < n; i++ {
+		if i%checkBlock == 0 {
+			select {
+			case <-done:
+				return ctx.Err()
+			default:
+			}
+		}
 		y.Xor(y, one)
-		y.Exp(y, two, mod)
+		t.Mul(y, y)
+		q.QuoRem(t, p.Modulus, y)
 	}
-	
-	x := gmp.NewInt(0).Set(c.x) // dont mutate c.x
-	if x.Cmp(y) == 0 {
-		return true, nil
+	return nil
+}
+
+// equalUpToSign reports whether y is x or -x modulo p, for x and y in
+// [0, p).
+func (p *Params) equalUpToSign(y, x *Int) bool {
+	neg := getInt()
+	defer putInt(neg)
+	neg.Sub(p.Modulus, x)
+	return p.equalAny(y, x, neg)
+}
+
+// equalAny reports whether y equals any of xs. The values are compared as
+// fixed-width bytes with crypto/subtle, all of them every time, so that how
+// long a check takes to reject a solution does not depend on how close it
+// came to the expected value. Values that are negative or wider than the
+// modulus, which only an out-of-range challenge produces, equal nothing.
+func (p *Params) equalAny(y *Int, xs ...*Int) bool {
+	w := (p.Modulus.BitLen() + 7) / 8
+	if !fitsBytes(y, w) {
+		return false
+	}
+	var buf [2 * encodeBufSize]byte
+	yb := appendFixedBytes(buf[:0], y, w)
+	eq := 0
+	for _, x := range xs {
+		if !fitsBytes(x, w) {
+			continue
+		}
+		xb := appendFixedBytes(yb[w:w], x, w)
+		eq |= subtle.ConstantTimeCompare(yb, xb)
+	}
+	return eq == 1
+}
+
+// fitsBytes reports whether x is non-negative and fits in w bytes.
+func fitsBytes(x *Int, w int) bool {
+	return x.Sign() >= 0 && x.BitLen() <= 8*w
+}
+
+// appendFixedBytes appends the big-endian bytes of x to dst, zero-padded on
+// the left to w bytes. x must fit in w bytes.
+func appendFixedBytes(dst []byte, x *Int, w int) []byte {
+	n := len(dst)
+	dst = appendValueBytes(dst, x)
+	k := len(dst) - n
+	dst = append(dst, make([]byte, w-k)...)
+	copy(dst[n+w-k:], dst[n:n+k])
+	for i := n; i < n+w-k; i++ {
+		dst[i] = 0
+	}
+	return dst
+}
+
+// isTwin reports whether y, the value after at least one iteration, cannot
+// be what Solve returns: y XOR 1 must be the square x^e. The twin of 1,
+// which follows 0, is p-1, for which y XOR 1 is p itself. It returns
+// errTwinModulus if p cannot tell twins apart; see RejectTwinSolutions.
+func (p *Params) isTwin(y *Int) (bool, error) {
+	if !p.detectsTwins() {
+		return false, errTwinModulus
+	}
+	r := getInt()
+	defer putInt(r)
+	r.Xor(y, one)
+	return r.Cmp(p.Modulus) >= 0 || jacobi(r, p.Modulus) < 0, nil
+}
+
+// detectsTwins reports whether the modulus is 7 mod 8, so that the
+// exponent is even and isTwin works. Validate has already checked that it
+// is 3 mod 4.
+func (p *Params) detectsTwins() bool {
+	return p.Modulus.Bit(2) == 1
+}
+
+var errTwinModulus = fmt.Errorf("%w: modulus is not 7 mod 8, so twin solutions cannot be rejected", ErrInvalidParams)
+
+// CheckByResolve verifies a solution by solving the challenge again and
+// comparing the results. Check squares away the sign at every step, so it
+// also accepts twins of the solution such as (-(y XOR 1)) XOR 1, which is
+// p-y, for a solution y; CheckByResolve accepts exactly the solution Solve
+// returns.
+//
+// The price is the cost of solving: Check undoes each iteration with a single
+// squaring, while CheckByResolve repeats the full exponentiation, about
+// log2(modulus) squarings per iteration. Check with RejectTwinSolutions set
+// accepts the same solutions, so prefer it unless you need an independent
+// recomputation.
+func (c *Challenge) CheckByResolve(s string) (bool, error) {
+	if c.d > MaxCheckDifficulty {
+		return false, ErrDifficultyTooHigh
+	}
+	if RejectWeakChallenges && c.IsWeak() {
+		return false, ErrWeakChallenge
+	}
+	y := getInt()
+	defer putInt(y)
+	if err := c.params().decodeSolutionTo(y, s); err != nil {
+		return false, fmt.Errorf("decode solution: %w", err)
 	}
-	x.Sub(mod, c.x)
-	return x.Cmp(y) == 0, nil
+	x := getInt()
+	defer putInt(x)
+	x, _, _ = c.solve(context.Background(), x)
+	return c.params().equalAny(y, x), nil
 }
@@ -1,7 +1,12 @@
 package pow
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -88,4 +93,882 @@ func TestSolvePerformance(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestCheckDifficultyCap(t *testing.T) {
+	c := &Challenge{d: MaxCheckDifficulty + 1, x: NewInt(12345)}
+	if _, err := c.Check("s.AA=="); !errors.Is(err, ErrDifficultyTooHigh) {
+		t.Fatalf("Check error = %v, want ErrDifficultyTooHigh", err)
+	}
+
+	defer func(old uint32) { MaxCheckDifficulty = old }(MaxCheckDifficulty)
+	MaxCheckDifficulty = 4
+	c = &Challenge{d: 4, x: NewInt(12345)}
+	if good, err := c.Check(c.Solve()); err != nil || !good {
+		t.Errorf("Check at the cap = %v, %v; want true, nil", good, err)
+	}
+	c.d = 5
+	if _, err := c.Check(c.Solve()); !errors.Is(err, ErrDifficultyTooHigh) {
+		t.Errorf("Check above lowered cap error = %v, want ErrDifficultyTooHigh", err)
+	}
+}
+
+func TestCloneReset(t *testing.T) {
+	c := &Challenge{d: 3, x: NewInt(12345)}
+	clone := c.Clone()
+	clone.x.SetInt64(1)
+	clone.d = 4
+	if c.d != 3 || c.x.Cmp(NewInt(12345)) != 0 {
+		t.Errorf("modifying clone changed original to d=%d x=%s", c.d, c.x)
+	}
+
+	x := c.x
+	v := NewInt(54321)
+	if err := c.Reset(7, v); err != nil {
+		t.Fatal(err)
+	}
+	if c.d != 7 || c.x.Cmp(v) != 0 {
+		t.Errorf("after Reset got d=%d x=%s, want d=7 x=%s", c.d, c.x, v)
+	}
+	if c.x != x {
+		t.Error("Reset replaced the value instead of reusing it")
+	}
+	v.SetInt64(0)
+	if c.x.Cmp(NewInt(54321)) != 0 {
+		t.Error("Reset retained its argument")
+	}
+
+	var zero Challenge
+	if err := zero.Reset(1, v); err != nil {
+		t.Fatal(err)
+	}
+	if zero.d != 1 || zero.x.Sign() != 0 {
+		t.Errorf("Reset on zero Challenge got d=%d x=%s", zero.d, zero.x)
+	}
+
+	for _, bad := range []struct {
+		x    *Int
+		want error
+	}{
+		{NewInt(0).Set(ParamsP1279.Modulus), ErrValueTooLarge},
+		{NewInt(0).Lsh(ParamsP1279.Modulus, 8), ErrValueTooLarge},
+		{NewInt(-1), ErrValueOutOfRange},
+	} {
+		if err := c.Reset(9, bad.x); !errors.Is(err, bad.want) {
+			t.Errorf("Reset(%x) = %v, want %v", bad.x, err, bad.want)
+		}
+		if c.d != 7 || c.x.Cmp(NewInt(54321)) != 0 {
+			t.Errorf("failed Reset changed the challenge to d=%d x=%s", c.d, c.x)
+		}
+	}
+}
+
+func TestParseDifficulty(t *testing.T) {
+	for _, tc := range []struct {
+		challenge string
+		want      uint32
+	}{
+		{"s.AAFfkA==.wxZVoJ86n1h9CNavECXG4w==", 90000},
+		{"s.AV+Q.wxZVoJ86n1h9CNavECXG4w==", 90000},
+		{"s.AAAAAA==.", 0},
+	} {
+		d, err := ParseDifficulty(tc.challenge)
+		if err != nil || d != tc.want {
+			t.Errorf("ParseDifficulty(%s) = %d, %v; want %d, nil", tc.challenge, d, err, tc.want)
+		}
+	}
+	for _, challenge := range []string{"", "s.AAFfkA==", "t.AAFfkA==.AA==", "s.AQIDBAU=.AA=="} {
+		if _, err := ParseDifficulty(challenge); err == nil {
+			t.Errorf("ParseDifficulty(%q) succeeded, want error", challenge)
+		}
+	}
+}
+
+func TestParameters(t *testing.T) {
+	m, e := Parameters()
+	if m.BitLen() != 1279 || e.BitLen() != 1278 {
+		t.Errorf("Parameters() bit lengths = %d, %d; want 1279, 1278", m.BitLen(), e.BitLen())
+	}
+	m.SetInt64(0)
+	e.SetInt64(0)
+	if mod.Sign() == 0 || exp.Sign() == 0 {
+		t.Error("modifying Parameters() results changed the package parameters")
+	}
+}
+
+func TestIsWeak(t *testing.T) {
+	minusOne := NewInt(0).Sub(mod, one)
+	for _, tc := range []struct {
+		x    *Int
+		weak bool
+	}{
+		{NewInt(0), true},
+		{NewInt(1), true},
+		{minusOne, true},
+		{NewInt(0).Set(mod), true},
+		{NewInt(0).Add(mod, one), true},
+		{NewInt(2), false},
+		{NewInt(12345), false},
+		{NewInt(0).Sub(minusOne, one), false},
+	} {
+		c := &Challenge{d: 10, x: tc.x}
+		if got := c.IsWeak(); got != tc.weak {
+			t.Errorf("IsWeak() for x=%s = %v, want %v", tc.x, got, tc.weak)
+		}
+	}
+
+	// -1 really is solved after a single iteration
+	c := &Challenge{d: 1, x: minusOne}
+	if s := c.Solve(); s != ParamsP1279.encodeSolution(NewInt(0)) {
+		t.Errorf("Solve() for x=-1 = %s, want zero", s)
+	}
+}
+
+func TestRejectWeakChallenges(t *testing.T) {
+	defer func() { RejectWeakChallenges = false }()
+	RejectWeakChallenges = true
+
+	weak := &Challenge{d: 10, x: NewInt(1)}
+	if _, err := weak.Check(weak.Solve()); !errors.Is(err, ErrWeakChallenge) {
+		t.Errorf("Check on weak challenge error = %v, want ErrWeakChallenge", err)
+	}
+	c := &Challenge{d: 10, x: NewInt(12345)}
+	if good, err := c.Check(c.Solve()); err != nil || !good {
+		t.Errorf("Check on strong challenge = %v, %v; want true, nil", good, err)
+	}
+}
+
+func TestDecodeChallengeTrailingNewline(t *testing.T) {
+	c := GenerateChallenge(3)
+	decoded, err := DecodeChallenge(c.String() + "\n")
+	if err != nil {
+		t.Fatalf("DecodeChallenge with trailing newline: %v", err)
+	}
+	if good, err := decoded.Check(c.Solve() + "\n"); err != nil || !good {
+		t.Errorf("Check with trailing newline = %v, %v; want true, nil", good, err)
+	}
+	if _, err := DecodeChallenge(c.String() + ".AA=="); err == nil {
+		t.Error("DecodeChallenge accepted an extra segment")
+	}
+}
+
+func TestForEach(t *testing.T) {
+	c := &Challenge{d: 6, x: NewInt(12345)}
+	var seen []*Int
+	c.ForEach(func(i uint32, x *Int) bool {
+		if int(i) != len(seen)+1 {
+			t.Errorf("ForEach index = %d, want %d", i, len(seen)+1)
+		}
+		seen = append(seen, NewInt(0).Set(x))
+		return true
+	})
+	if len(seen) != 6 {
+		t.Fatalf("ForEach yielded %d values, want 6", len(seen))
+	}
+	for i, x := range seen {
+		prefix := &Challenge{d: uint32(i + 1), x: c.x}
+		if want := prefix.Solve(); ParamsP1279.encodeSolution(x) != want {
+			t.Errorf("value after iteration %d = %s, want %s", i+1, ParamsP1279.encodeSolution(x), want)
+		}
+	}
+
+	n := 0
+	c.ForEach(func(i uint32, x *Int) bool {
+		n++
+		return i < 2
+	})
+	if n != 2 {
+		t.Errorf("ForEach ran %d iterations after fn returned false, want 2", n)
+	}
+	if c.x.Cmp(NewInt(12345)) != 0 {
+		t.Error("ForEach modified the challenge")
+	}
+}
+
+func TestForEachTwoCycle(t *testing.T) {
+	c := &Challenge{d: 4, x: NewInt(0)}
+	var got []int64
+	c.ForEach(func(i uint32, x *Int) bool {
+		got = append(got, x.Int64())
+		return true
+	})
+	if fmt.Sprint(got) != "[1 0 1 0]" {
+		t.Errorf("trajectory of 0 = %v, want [1 0 1 0]", got)
+	}
+}
+
+func TestZeroValueChallenge(t *testing.T) {
+	var c Challenge
+	if s := c.String(); s != "s.AAAAAA==." {
+		t.Errorf("String() = %q, want %q", s, "s.AAAAAA==.")
+	}
+	c.d = 3
+	zero := &Challenge{d: 3, x: NewInt(0)}
+	if got, want := c.Solve(), zero.Solve(); got != want {
+		t.Errorf("Solve() = %s, want %s", got, want)
+	}
+	if good, err := c.Check(zero.Solve()); err != nil || !good {
+		t.Errorf("Check = %v, %v; want true, nil", good, err)
+	}
+	if !c.IsWeak() || c.WorkUnits() != 0 {
+		t.Errorf("IsWeak() = %v, WorkUnits() = %d; want true, 0", c.IsWeak(), c.WorkUnits())
+	}
+	if clone := c.Clone(); clone.x == nil || clone.x.Sign() != 0 {
+		t.Error("Clone() of unset value is not zero")
+	}
+	c.ForEach(func(i uint32, x *Int) bool { return true })
+}
+
+func TestValid(t *testing.T) {
+	for _, tc := range []struct {
+		c   *Challenge
+		err error
+	}{
+		{&Challenge{d: 10}, ErrWeakChallenge},
+		{&Challenge{d: 10, x: NewInt(1)}, ErrWeakChallenge},
+		{&Challenge{d: 10, x: NewInt(0).Set(mod)}, ErrValueOutOfRange},
+		{&Challenge{d: 10, x: NewInt(-5)}, ErrValueOutOfRange},
+		{&Challenge{d: 10, x: NewInt(12345)}, nil},
+	} {
+		if err := tc.c.Valid(); !errors.Is(err, tc.err) {
+			t.Errorf("Valid() for %s = %v, want %v", tc.c, err, tc.err)
+		}
+	}
+}
+
+func TestCanonicalEncoding(t *testing.T) {
+	defer func(old uint32) { MaxCheckDifficulty = old }(MaxCheckDifficulty)
+	MaxCheckDifficulty = 1<<32 - 1
+	challenges := []*Challenge{
+		{d: 0, x: NewInt(0)},
+		{d: 1, x: NewInt(1)},
+		{d: 1<<32 - 1, x: NewInt(255)},
+		{d: 256, x: NewInt(256)},
+		{d: 7, x: NewInt(0).Sub(mod, one)},
+		{d: 7, x: NewInt(0).Sub(ParamsP2203.Modulus, one), p: ParamsP2203},
+	}
+	for i := 0; i < 100; i++ {
+		challenges = append(challenges, GenerateChallenge(uint32(i)))
+	}
+	for _, c := range challenges {
+		s := c.String()
+		decoded, err := DecodeChallenge(s)
+		if err != nil {
+			t.Fatalf("DecodeChallenge(%s): %v", s, err)
+		}
+		if got := decoded.String(); got != s {
+			t.Errorf("DecodeChallenge(%s).String() = %s", s, got)
+		}
+	}
+
+	// kCTF pads numbers to a multiple of three bytes
+	c, err := DecodeChallenge("s.AV+Q.ADA5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.String(), "s.AAFfkA==.MDk="; got != want {
+		t.Errorf("canonical form of kCTF challenge = %s, want %s", got, want)
+	}
+}
+
+func TestCheckRejectsUnreducedSolution(t *testing.T) {
+	c := &Challenge{d: 3, x: NewInt(12345)}
+	y, err := ParamsP1279.decodeSolution(c.Solve())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []*Int{NewInt(0).Add(y, mod), NewInt(0).Set(mod)} {
+		good, err := c.Check(ParamsP1279.encodeSolution(v))
+		if good || !errors.Is(err, ErrValueTooLarge) || !errors.Is(err, ErrValueOutOfRange) {
+			t.Errorf("Check(%s) = %v, %v; want false, ErrValueTooLarge", v, good, err)
+		}
+	}
+}
+
+func TestCheckByResolve(t *testing.T) {
+	c := GenerateChallenge(20)
+	s := c.Solve()
+	if ok, err := c.CheckByResolve(s); !ok || err != nil {
+		t.Errorf("CheckByResolve(Solve()) = %v, %v; want true, nil", ok, err)
+	}
+
+	// negating y XOR 1 survives the squaring in Check
+	y, err := ParamsP1279.decodeSolution(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y.Xor(y, one)
+	y.Sub(mod, y)
+	ts := ParamsP1279.encodeSolution(y.Xor(y, one))
+	if ok, err := c.Check(ts); !ok || err != nil {
+		t.Fatalf("Check(twin solution) = %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := c.CheckByResolve(ts); ok || err != nil {
+		t.Errorf("CheckByResolve(twin solution) = %v, %v; want false, nil", ok, err)
+	}
+
+	if _, err := (&Challenge{d: MaxCheckDifficulty + 1, x: NewInt(2)}).CheckByResolve(s); err != ErrDifficultyTooHigh {
+		t.Errorf("CheckByResolve above the cap: err = %v, want %v", err, ErrDifficultyTooHigh)
+	}
+}
+
+func TestSolveShortCycleMatchesIteration(t *testing.T) {
+	for _, p := range []*Params{ParamsP1279, ParamsP2203} {
+		for _, start := range []int64{0, 1} {
+			// parity: 0 and 1 swap on every iteration
+			want := start
+			for d := uint32(0); d < 6; d++ {
+				c := &Challenge{d: d, x: NewInt(start), p: p}
+				iterated := NewInt(start)
+				c.ForEach(func(i uint32, x *Int) bool {
+					iterated.Set(x)
+					return true
+				})
+				if iterated.Cmp(NewInt(want)) != 0 {
+					t.Errorf("%s: %d iterations from %d = %s, parity gives %d", p.Version, d, start, iterated, want)
+				}
+				got, _, _ := c.solve(context.Background(), NewInt(0))
+				if got.Cmp(iterated) != 0 {
+					t.Errorf("%s: solve from %d with d=%d = %s, want %s", p.Version, start, d, got, iterated)
+				}
+				want ^= 1
+			}
+		}
+	}
+
+}
+
+func TestDecodeChallengeDetailed(t *testing.T) {
+	const v = "s.AV+Q.ADA5" // kCTF pads numbers to a multiple of three bytes
+	c, parts, err := DecodeChallengeDetailed(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := DecodeChallenge(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.String() != want.String() || c.params() != want.params() {
+		t.Errorf("DecodeChallengeDetailed = %s, DecodeChallenge = %s", c, want)
+	}
+	if parts.Version != "s" || parts.Difficulty != "AV+Q" || parts.Value != "ADA5" {
+		t.Errorf("segments = %q %q %q", parts.Version, parts.Difficulty, parts.Value)
+	}
+	if fmt.Sprintf("%x %x", parts.DifficultyBytes, parts.ValueBytes) != "015f90 003039" {
+		t.Errorf("decoded bytes = %x %x, want 015f90 003039", parts.DifficultyBytes, parts.ValueBytes)
+	}
+	if _, _, err := DecodeChallengeDetailed("s.AV+Q"); err == nil {
+		t.Error("DecodeChallengeDetailed accepted a challenge without a value")
+	}
+}
+
+func TestGenerateChallengeChecked(t *testing.T) {
+	if _, err := GenerateChallengeChecked(0); err != ErrDifficultyTooLow {
+		t.Errorf("GenerateChallengeChecked(0): err = %v, want %v", err, ErrDifficultyTooLow)
+	}
+	c, err := GenerateChallengeChecked(1)
+	if err != nil || c.d != 1 {
+		t.Fatalf("GenerateChallengeChecked(1) = %v, %v", c, err)
+	}
+
+	defer func(old uint32) { MinDifficulty = old }(MinDifficulty)
+	MinDifficulty = 100
+	if _, err := GenerateChallengeChecked(99); err != ErrDifficultyTooLow {
+		t.Errorf("GenerateChallengeChecked(99) with MinDifficulty 100: err = %v, want %v", err, ErrDifficultyTooLow)
+	}
+	if _, err := GenerateChallengeChecked(100); err != nil {
+		t.Errorf("GenerateChallengeChecked(100) with MinDifficulty 100: %v", err)
+	}
+	MinDifficulty = 0
+	if _, err := GenerateChallengeChecked(0); err != ErrDifficultyTooLow {
+		t.Errorf("GenerateChallengeChecked(0) with MinDifficulty 0: err = %v, want %v", err, ErrDifficultyTooLow)
+	}
+}
+
+func TestNewChallenge(t *testing.T) {
+	c, err := NewChallenge(90000, []byte{0, 0xc3, 0x16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.String(), "s.AAFfkA==.wxY="; got != want {
+		t.Errorf("NewChallenge = %s, want %s", got, want)
+	}
+	if _, err := NewChallenge(1, nil); err != nil {
+		t.Errorf("NewChallenge with empty value: %v", err)
+	}
+	if _, err := NewChallenge(1, mod.Bytes()); !errors.Is(err, ErrValueOutOfRange) {
+		t.Errorf("NewChallenge with the modulus: err = %v, want %v", err, ErrValueOutOfRange)
+	}
+	max := NewInt(0).Sub(mod, one).Bytes()
+	if _, err := NewChallenge(1, max); err != nil {
+		t.Errorf("NewChallenge with modulus-1: %v", err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want error
+	}{
+		{"", ErrBadVersion},
+		{"x.AAAAAQ==.AA==", ErrBadVersion},
+		{"s2.AAAAAQ==.AA==", ErrBadVersion},
+		{"s.AAAAAQ==", ErrBadEncoding},
+		{"s.AAAAAQ==.AA==.AA==", ErrBadEncoding},
+		{"s.AAAAAQ==.!!", ErrBadEncoding},
+		{"s.!!.AA==", ErrBadEncoding},
+		{"s.AAAAAQ==.A\x00==", ErrBadEncoding},
+		{"s.AQAAAAA=.AA==", ErrBadDifficulty},
+		{"s.AQAAAAAAAAA=.AA==", ErrBadDifficulty},
+		{"s.AAAAAQ==." + strings.Repeat("/", 300), ErrValueOutOfRange},
+	}
+	for _, tt := range tests {
+		if _, err := DecodeChallenge(tt.in); !errors.Is(err, tt.want) {
+			t.Errorf("DecodeChallenge(%q): err = %v, want %v", tt.in, err, tt.want)
+		}
+	}
+
+	c := &Challenge{d: 1, x: NewInt(2)}
+	for in, want := range map[string]error{
+		"x.AA==":                        ErrBadVersion,
+		"s.!!":                          ErrBadEncoding,
+		"s." + strings.Repeat("/", 300): ErrValueOutOfRange,
+		ParamsP1279.encodeSolution(mod): ErrValueOutOfRange,
+	} {
+		if _, err := c.Check(in); !errors.Is(err, want) {
+			t.Errorf("Check(%.20q): err = %v, want %v", in, err, want)
+		}
+	}
+}
+
+func TestStringURL(t *testing.T) {
+	c := &Challenge{d: 0xfbff, x: NewInt(0xfbefff)}
+	u := c.StringURL()
+	if want := "s.AAD7_w.--__"; u != want {
+		t.Errorf("StringURL() = %s, want %s", u, want)
+	}
+	for _, v := range []string{u, c.String()} {
+		got, err := DecodeChallengeURL(v)
+		if err != nil {
+			t.Fatalf("DecodeChallengeURL(%s): %v", v, err)
+		}
+		if got.String() != c.String() {
+			t.Errorf("DecodeChallengeURL(%s) = %s, want %s", v, got, c)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		c := GenerateChallenge(uint32(i) * 1000)
+		got, err := DecodeChallengeURL(c.StringURL())
+		if err != nil || got.String() != c.String() {
+			t.Errorf("DecodeChallengeURL(%s) = %v, %v; want %s", c.StringURL(), got, err, c)
+		}
+	}
+}
+
+func TestDecodeUnpadded(t *testing.T) {
+	const want = "s.AAFfkA==.wxZVoJ86n1h9CNavECXG4w=="
+	for _, v := range []string{
+		want,
+		"s.AAFfkA.wxZVoJ86n1h9CNavECXG4w",
+		"s.AAFfkA==.wxZVoJ86n1h9CNavECXG4w",
+		"s.AAFfkA.wxZVoJ86n1h9CNavECXG4w==",
+	} {
+		c, err := DecodeChallenge(v)
+		if err != nil {
+			t.Errorf("DecodeChallenge(%s): %v", v, err)
+			continue
+		}
+		if c.String() != want {
+			t.Errorf("DecodeChallenge(%s) = %s, want %s", v, c, want)
+		}
+	}
+	for _, v := range []string{"s.AAFfkA=.wxY=", "s.AAFfkA==.wx+_"} {
+		if _, err := DecodeChallenge(v); !errors.Is(err, ErrBadEncoding) {
+			t.Errorf("DecodeChallenge(%s): err = %v, want %v", v, err, ErrBadEncoding)
+		}
+	}
+
+	c := GenerateChallenge(2)
+	s := c.Solve()
+	if ok, err := c.Check(strings.TrimRight(s, "=")); !ok || err != nil {
+		t.Errorf("Check(unpadded %s) = %v, %v; want true, nil", s, ok, err)
+	}
+}
+
+func TestDecodeChallengeRejectsUnreducedValue(t *testing.T) {
+	for _, x := range []*Int{mod, NewInt(0).Add(mod, one), NewInt(0).Lsh(one, 1279)} {
+		c := &Challenge{d: 1, x: x}
+		decoders := map[string]func(string) (*Challenge, error){
+			c.String():        DecodeChallenge,
+			c.StringURL():     DecodeChallengeURL,
+			c.CompactString(): DecodeCompact,
+			c.EncodeHex():     DecodeChallengeHex,
+		}
+		for v, decode := range decoders {
+			if _, err := decode(v); !errors.Is(err, ErrValueTooLarge) {
+				t.Errorf("decoding %.20s... with x = %s: err = %v, want %v", v, x, err, ErrValueTooLarge)
+			}
+		}
+	}
+	if _, err := DecodeChallenge((&Challenge{d: 1, x: NewInt(0).Sub(mod, one)}).String()); err != nil {
+		t.Errorf("DecodeChallenge with x = modulus-1: %v", err)
+	}
+}
+
+func TestCheckRejectsHugeSolutionCheaply(t *testing.T) {
+	huge := "s." + strings.Repeat("/", 10<<20)
+	c := &Challenge{d: 1 << 20, x: NewInt(2)}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := c.Check(huge)
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, ErrValueOutOfRange) {
+		t.Errorf("Check with a 10 MiB solution: err = %v, want %v", err, ErrValueOutOfRange)
+	}
+	// the length is checked before anything is decoded
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("Check with a 10 MiB solution allocated %d bytes", n)
+	}
+}
+
+func TestDifficultyLimit(t *testing.T) {
+	c := &Challenge{d: MaxCheckDifficulty + 1, x: NewInt(2)}
+	v := c.String()
+	if _, err := DecodeChallenge(v); err != ErrDifficultyTooHigh {
+		t.Errorf("DecodeChallenge above MaxCheckDifficulty: err = %v, want %v", err, ErrDifficultyTooHigh)
+	}
+	if _, err := DecodeCompact(c.CompactString()); err != ErrDifficultyTooHigh {
+		t.Errorf("DecodeCompact above MaxCheckDifficulty: err = %v, want %v", err, ErrDifficultyTooHigh)
+	}
+	if _, err := DecodeChallengeHex(c.EncodeHex()); err != ErrDifficultyTooHigh {
+		t.Errorf("DecodeChallengeHex above MaxCheckDifficulty: err = %v, want %v", err, ErrDifficultyTooHigh)
+	}
+
+	decoded, err := DecodeChallengeLimit(v, c.d)
+	if err != nil {
+		t.Fatalf("DecodeChallengeLimit at the limit: %v", err)
+	}
+	if _, err := DecodeChallengeLimit(v, c.d-1); err != ErrDifficultyTooHigh {
+		t.Errorf("DecodeChallengeLimit below the difficulty: err = %v, want %v", err, ErrDifficultyTooHigh)
+	}
+	if _, err := decoded.CheckLimit("s.AA==", 10); err != ErrDifficultyTooHigh {
+		t.Errorf("CheckLimit below the difficulty: err = %v, want %v", err, ErrDifficultyTooHigh)
+	}
+
+	small := GenerateChallenge(3)
+	if ok, err := small.CheckLimit(small.Solve(), 3); !ok || err != nil {
+		t.Errorf("CheckLimit at the difficulty = %v, %v; want true, nil", ok, err)
+	}
+}
+
+func TestEdgeSolutions(t *testing.T) {
+	for _, p := range []*Params{ParamsP1279, ParamsP2203} {
+		if got, want := p.edges, [2]string{p.Version + ".", p.Version + ".AQ=="}; got != want {
+			t.Errorf("%s edges = %q, want %q", p.Version, got, want)
+		}
+		for i, s := range p.edges {
+			y, err := p.decodeSolution(s)
+			if err != nil || y.Cmp(NewInt(int64(i))) != 0 {
+				t.Errorf("decodeSolution(%q) = %v, %v; want %d", s, y, err, i)
+			}
+		}
+	}
+
+	// 0 and 1 alternate, so an even difficulty returns x itself
+	for _, x := range []int64{0, 1} {
+		c := &Challenge{d: 4, x: NewInt(x)}
+		s := c.Solve()
+		if s != ParamsP1279.edges[x] {
+			t.Errorf("Solve() for x=%d = %q, want %q", x, s, ParamsP1279.edges[x])
+		}
+		if ok, err := c.Check(s); !ok || err != nil {
+			t.Errorf("Check(%q) = %v, %v; want true, nil", s, ok, err)
+		}
+	}
+	zero := &Challenge{d: 2, x: NewInt(0)}
+	if ok, err := zero.Check("s.AAAA"); !ok || err != nil {
+		t.Errorf("Check of kCTF-padded zero = %v, %v; want true, nil", ok, err)
+	}
+
+	// Params built by hand still encode the edge cases
+	p := &Params{Modulus: ParamsP1279.Modulus, Exponent: ParamsP1279.Exponent, Version: "s"}
+	if got := p.encodeSolution(NewInt(1)); got != "s.AQ==" {
+		t.Errorf("encodeSolution(1) without edges = %q", got)
+	}
+}
+
+func TestCheckContext(t *testing.T) {
+	c := GenerateChallenge(5)
+	s := c.Solve()
+	if ok, err := c.CheckContext(context.Background(), s); !ok || err != nil {
+		t.Errorf("CheckContext = %v, %v; want true, nil", ok, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if ok, err := c.CheckContext(ctx, s); ok || err != context.Canceled {
+		t.Errorf("cancelled CheckContext = %v, %v; want false, context.Canceled", ok, err)
+	}
+
+	// A challenge at the difficulty limit stops promptly
+	big := &Challenge{d: MaxCheckDifficulty, x: NewInt(12345)}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := big.CheckContext(ctx, s); err != context.DeadlineExceeded {
+		t.Errorf("CheckContext error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("CheckContext took %v to stop", elapsed)
+	}
+}
+
+func TestRequireCanonicalSolutions(t *testing.T) {
+	defer func() { RequireCanonicalSolutions = false }()
+	c := GenerateChallenge(3)
+	s := c.Solve()
+	y, _ := ParamsP1279.decodeSolution(s)
+	b := y.Bytes()
+	padded := "s." + base64.StdEncoding.EncodeToString(append([]byte{0}, b...))
+	unpadded := "s." + base64.RawStdEncoding.EncodeToString(b)
+
+	for _, strict := range []bool{false, true} {
+		RequireCanonicalSolutions = strict
+		if ok, err := c.Check(s + "\n"); !ok || err != nil {
+			t.Errorf("strict=%v: Check(canonical) = %v, %v; want true, nil", strict, ok, err)
+		}
+		for _, other := range []string{padded, unpadded} {
+			if other == s {
+				continue // no base64 padding to drop
+			}
+			ok, err := c.Check(other)
+			if strict && (ok || !errors.Is(err, ErrNonCanonical) || !errors.Is(err, ErrBadEncoding)) {
+				t.Errorf("strict Check(%s) = %v, %v; want ErrNonCanonical", other, ok, err)
+			}
+			if !strict && (!ok || err != nil) {
+				t.Errorf("lenient Check(%s) = %v, %v; want true, nil", other, ok, err)
+			}
+		}
+	}
+}
+
+func TestEqualAny(t *testing.T) {
+	for _, p := range []*Params{ParamsP1279, ParamsP2203} {
+		max := NewInt(0).Sub(p.Modulus, one)
+		small := NewInt(5)
+		if !p.equalAny(small, max, NewInt(5)) {
+			t.Error("equalAny missed a match after a mismatch")
+		}
+		if p.equalAny(small, max, zero) {
+			t.Error("equalAny matched unequal values")
+		}
+		if !p.equalAny(zero, zero) || !p.equalAny(max, max) {
+			t.Error("equalAny missed a match at the ends of the range")
+		}
+		// Values differing only in length must not compare equal
+		if p.equalAny(NewInt(0x100), NewInt(0x1)) {
+			t.Error("equalAny ignored leading bytes")
+		}
+		wide := NewInt(0).Lsh(p.Modulus, 8)
+		if p.equalAny(small, wide, NewInt(-5)) || p.equalAny(wide, wide) {
+			t.Error("equalAny matched a value outside the field")
+		}
+	}
+}
+
+func TestCheckOversizedChallenge(t *testing.T) {
+	for _, d := range []uint32{0, 2} {
+		c := &Challenge{d: d, x: NewInt(0).Lsh(ParamsP1279.Modulus, 8)}
+		// With d = 0 Solve returns x itself, which does not decode
+		for _, s := range []string{c.Solve(), (&Challenge{d: d, x: NewInt(0)}).Solve()} {
+			if ok, _ := c.Check(s); ok {
+				t.Errorf("d=%d: Check(%s) = true, want false", d, s)
+			}
+		}
+	}
+}
+
+func TestRequireCanonicalChallenges(t *testing.T) {
+	defer func() { RequireCanonicalChallenges = false }()
+	c := GenerateChallenge(300)
+	s := c.String()
+	x := c.value().Bytes()
+	shortD := "s." + base64.StdEncoding.EncodeToString([]byte{1, 44}) + "." + base64.StdEncoding.EncodeToString(x)
+	paddedX := "s." + base64.StdEncoding.EncodeToString(EncodeDifficulty(300)) + "." + base64.StdEncoding.EncodeToString(append([]byte{0}, x...))
+
+	for _, strict := range []bool{false, true} {
+		RequireCanonicalChallenges = strict
+		if _, err := DecodeChallenge(s + "\n"); err != nil {
+			t.Errorf("strict=%v: DecodeChallenge(canonical) error = %v", strict, err)
+		}
+		for _, other := range []string{shortD, paddedX, c.StringURL()} {
+			if other == s {
+				continue // nothing URL-safe encoding changes
+			}
+			d, err := DecodeChallenge(other)
+			if strict && (!errors.Is(err, ErrNonCanonicalChallenge) || !errors.Is(err, ErrBadEncoding)) {
+				t.Errorf("strict DecodeChallenge(%s) error = %v, want ErrNonCanonicalChallenge", other, err)
+			}
+			if !strict && (err != nil || d.String() != s) {
+				t.Errorf("lenient DecodeChallenge(%s) = %v, %v; want %s", other, d, err, s)
+			}
+		}
+	}
+}
+
+func TestRequireCanonicalChallengesOtherDecoders(t *testing.T) {
+	defer func() { RequireCanonicalChallenges = false }()
+	c := GenerateChallenge(300)
+	s := c.String()
+	x := c.value().Bytes()
+	shortD := "s." + base64.StdEncoding.EncodeToString([]byte{1, 44}) + "." + base64.StdEncoding.EncodeToString(x)
+	bin, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paddedBin := append(append([]byte{}, bin[:binaryHeaderSize]...), 0)
+	paddedBin = append(paddedBin, x...)
+	paddedBin[6]++
+
+	decoders := []struct {
+		name      string
+		decode    func(string) (*Challenge, error)
+		canonical string
+		other     string
+	}{
+		{"Params.Decode", func(v string) (*Challenge, error) {
+			p, err := ParamsP1279.Decode(v)
+			c, _ := p.(*Challenge)
+			return c, err
+		}, s, shortD},
+		{"DecodeChallengeHex", DecodeChallengeHex, c.EncodeHex(), strings.Replace(c.EncodeHex(), "12c", "12C", 1)},
+		{"DecodeCompact", DecodeCompact, c.CompactString(), "cs.0" + strings.TrimPrefix(c.CompactString(), "cs.")},
+		{"UnmarshalBinary", func(v string) (*Challenge, error) {
+			var c Challenge
+			return &c, c.UnmarshalBinary([]byte(v))
+		}, string(bin), string(paddedBin)},
+	}
+	for _, strict := range []bool{false, true} {
+		RequireCanonicalChallenges = strict
+		for _, tc := range decoders {
+			if got, err := tc.decode(tc.canonical); err != nil || got.String() != s {
+				t.Errorf("strict=%v: %s(canonical) = %v, %v; want %s", strict, tc.name, got, err, s)
+			}
+			got, err := tc.decode(tc.other)
+			if strict && !errors.Is(err, ErrNonCanonicalChallenge) {
+				t.Errorf("strict %s(%q) error = %v, want ErrNonCanonicalChallenge", tc.name, tc.other, err)
+			}
+			if !strict && (err != nil || got.String() != s) {
+				t.Errorf("lenient %s(%q) = %v, %v; want %s", tc.name, tc.other, got, err, s)
+			}
+		}
+	}
+}
+
+func TestRejectTwinSolutions(t *testing.T) {
+	defer func() { RejectTwinSolutions = false }()
+	challenges := []*Challenge{{d: 1, x: NewInt(0)}, {d: 2, x: NewInt(1)}}
+	for i := 0; i < 8; i++ {
+		challenges = append(challenges, GenerateChallenge(uint32(1+i%3)), ParamsP2203.GenerateChallenge(2))
+	}
+	for _, c := range challenges {
+		p := c.params()
+		s := c.Solve()
+		y, _ := p.decodeSolution(s)
+		twin := p.encodeSolution(NewInt(0).Sub(p.Modulus, y))
+		for _, strict := range []bool{false, true} {
+			RejectTwinSolutions = strict
+			if ok, err := c.Check(s); !ok || err != nil {
+				t.Errorf("%v strict=%v: Check(solution) = %v, %v; want true, nil", c, strict, ok, err)
+			}
+			if ok, err := c.Check(twin); ok == strict || err != nil {
+				t.Errorf("%v strict=%v: Check(twin) = %v, %v; want %v, nil", c, strict, ok, err, !strict)
+			}
+		}
+	}
+}
+
+func TestRejectTwinSolutionsResidue(t *testing.T) {
+	defer func() { RejectTwinSolutions = false }()
+	prime := func(mod8 int64) *Params {
+		m := NewInt(1)
+		m.Lsh(m, 199)
+		m.Add(m, NewInt(mod8))
+		for !m.ProbablyPrime(20) {
+			m.Add(m, NewInt(8))
+		}
+		e := NewInt(0).Add(m, one)
+		return &Params{Modulus: m, Exponent: e.Rsh(e, 2), Version: "t200"}
+	}
+	p3, p7 := prime(3), prime(7)
+
+	RejectTwinSolutions = true
+	if err := p3.Validate(); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("Validate(3 mod 8) = %v; want ErrInvalidParams", err)
+	}
+	if err := p7.Validate(); err != nil {
+		t.Errorf("Validate(7 mod 8) = %v; want nil", err)
+	}
+	RejectTwinSolutions = false
+	if err := p3.Validate(); err != nil {
+		t.Errorf("Validate(3 mod 8) without RejectTwinSolutions = %v; want nil", err)
+	}
+
+	for i := 0; i < 40; i++ {
+		for _, p := range []*Params{p3, p7} {
+			c := p.GenerateChallenge(uint32(1 + i%3))
+			s := c.Solve()
+			RejectTwinSolutions = true
+			ok, err := c.Check(s)
+			RejectTwinSolutions = false
+			if p == p3 {
+				if ok || !errors.Is(err, ErrInvalidParams) {
+					t.Errorf("%v: Check(solution) modulo 3 mod 8 = %v, %v; want false, ErrInvalidParams", c, ok, err)
+				}
+			} else if !ok || err != nil {
+				t.Errorf("%v: Check(solution) modulo 7 mod 8 = %v, %v; want true, nil", c, ok, err)
+			}
+		}
+	}
+}
+
+func TestCheckDispatchesOnVersion(t *testing.T) {
+	c := GenerateChallenge(5)
+	proof, err := c.SolveCheckpointed(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := c.Check(proof.String()); !ok || err != nil {
+		t.Errorf("Check(checkpoint proof) = %v, %v; want true, nil", ok, err)
+	}
+	other, _ := GenerateChallenge(5).SolveCheckpointed(context.Background(), 2)
+	if ok, err := c.Check(other.String()); ok || err != nil {
+		t.Errorf("Check(proof for another challenge) = %v, %v; want false, nil", ok, err)
+	}
+
+	// The proof is held to the same encoding rules as a plain solution
+	unpadded := strings.Replace(proof.String(), "==.", ".", 1)
+	if ok, err := c.Check(unpadded); !ok || err != nil {
+		t.Errorf("lenient Check(unpadded proof) = %v, %v; want true, nil", ok, err)
+	}
+	RequireCanonicalSolutions = true
+	ok, err := c.Check(unpadded)
+	RequireCanonicalSolutions = false
+	if ok || !errors.Is(err, ErrNonCanonical) {
+		t.Errorf("strict Check(unpadded proof) = %v, %v; want ErrNonCanonical", ok, err)
+	}
+
+	d := ParamsP2203.GenerateChallenge(1)
+	for in, unsupported := range map[string]bool{
+		d.Solve():          false,
+		"k" + d.Solve():    false,
+		"z.AA==":           true,
+		"kz.AAAAAQ==.AA==": true,
+		"":                 true,
+	} {
+		_, err := c.Check(in)
+		if !errors.Is(err, ErrBadVersion) || errors.Is(err, ErrUnsupportedVersion) != unsupported {
+			t.Errorf("Check(%.20q): err = %v, want ErrBadVersion, unsupported %v", in, err, unsupported)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Package powredis stores issued and solved challenges in Redis, so that
+// several instances of a service share one record of them. Store implements
+// both pow.ChallengeStore, for pow.Server, and pow.ReplayClaimer, for
+// Challenge.CheckOnce. Expiry is left to Redis, and claiming a challenge is a
+// single SET NX, so no two instances can accept the same one.
+//
+// The package does not depend on a Redis client. Any client can be used
+// through a Doer; with go-redis, for example:
+//
+//	store := powredis.New(powredis.DoerFunc(func(ctx context.Context, args ...interface{}) (interface{}, error) {
+//		v, err := rdb.Do(ctx, args...).Result()
+//		if err == redis.Nil {
+//			return nil, nil
+//		}
+//		return v, err
+//	}), "myservice:")
+package powredis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// A Doer sends a command to Redis and returns its reply. Nil replies must be
+// returned as a nil value and a nil error, and integer replies as int64.
+type Doer interface {
+	Do(ctx context.Context, args ...interface{}) (interface{}, error)
+}
+
+// DoerFunc adapts a function to a Doer.
+type DoerFunc func(ctx context.Context, args ...interface{}) (interface{}, error)
+
+// Do calls f.
+func (f DoerFunc) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return f(ctx, args...)
+}
+
+// Store keeps challenges in Redis under a key prefix. It is safe for
+// concurrent use if its Doer is.
+type Store struct {
+	d      Doer
+	prefix string
+
+	// Timeout bounds each command. Zero means no timeout.
+	Timeout time.Duration
+}
+
+// New returns a Store sending commands through d, with keys starting with
+// prefix.
+func New(d Doer, prefix string) *Store {
+	return &Store{d: d, prefix: prefix}
+}
+
+func (s *Store) do(args ...interface{}) (interface{}, error) {
+	ctx := context.Background()
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	return s.d.Do(ctx, args...)
+}
+
+func (s *Store) issuedKey(challenge string) string { return s.prefix + "issued:" + challenge }
+func (s *Store) solvedKey(challenge string) string { return s.prefix + "solved:" + challenge }
+
+// Add implements pow.ChallengeStore. Challenges that have already expired
+// are not stored.
+func (s *Store) Add(challenge string, expiry time.Time) error {
+	ttl := time.Until(expiry)
+	if ttl < time.Millisecond {
+		return nil
+	}
+	_, err := s.do("SET", s.issuedKey(challenge), "1", "PX", ttl.Milliseconds())
+	return err
+}
+
+// Contains implements pow.ChallengeStore.
+func (s *Store) Contains(challenge string) (bool, error) {
+	return s.count("EXISTS", s.issuedKey(challenge))
+}
+
+// Remove implements pow.ChallengeStore.
+func (s *Store) Remove(challenge string) (bool, error) {
+	return s.count("DEL", s.issuedKey(challenge))
+}
+
+// Sweep implements pow.ChallengeStore. It does nothing, as Redis expires
+// keys by itself.
+func (s *Store) Sweep(now time.Time) error {
+	return nil
+}
+
+// Seen implements pow.ReplayStore.
+func (s *Store) Seen(key string) (bool, error) {
+	return s.count("EXISTS", s.solvedKey(key))
+}
+
+// Mark implements pow.ReplayStore.
+func (s *Store) Mark(key string, ttl time.Duration) error {
+	_, err := s.do("SET", s.solvedKey(key), "1", "PX", millis(ttl))
+	return err
+}
+
+// Claim implements pow.ReplayClaimer.
+func (s *Store) Claim(key string, ttl time.Duration) (bool, error) {
+	v, err := s.do("SET", s.solvedKey(key), "1", "NX", "PX", millis(ttl))
+	if err != nil {
+		return false, err
+	}
+	return v != nil, nil
+}
+
+// count runs a command with an integer reply, reporting whether it was
+// positive.
+func (s *Store) count(args ...interface{}) (bool, error) {
+	v, err := s.do(args...)
+	if err != nil {
+		return false, err
+	}
+	n, ok := v.(int64)
+	if !ok {
+		return false, fmt.Errorf("powredis: unexpected reply %T to %v", v, args[0])
+	}
+	return n > 0, nil
+}
+
+// millis converts ttl to the milliseconds PX expects, which must be positive.
+func millis(ttl time.Duration) int64 {
+	if ttl < time.Millisecond {
+		return 1
+	}
+	return ttl.Milliseconds()
+}
@@ -0,0 +1,94 @@
+package powredis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redpwn/pow"
+)
+
+var (
+	_ pow.ChallengeStore = (*Store)(nil)
+	_ pow.ReplayClaimer  = (*Store)(nil)
+)
+
+// fakeRedis implements the commands Store sends, ignoring expiry.
+type fakeRedis struct {
+	mu   sync.Mutex
+	keys map[string]string
+	down bool
+}
+
+func (f *fakeRedis) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return nil, errors.New("connection refused")
+	}
+	key := args[1].(string)
+	_, exists := f.keys[key]
+	switch args[0] {
+	case "SET":
+		if args[3] == "NX" && exists {
+			return nil, nil
+		}
+		f.keys[key] = args[2].(string)
+		return "OK", nil
+	case "EXISTS", "DEL":
+		if args[0] == "DEL" {
+			delete(f.keys, key)
+		}
+		if exists {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	}
+	return nil, errors.New("unknown command")
+}
+
+func TestServerStore(t *testing.T) {
+	s := pow.NewServer(2, time.Minute, New(&fakeRedis{keys: make(map[string]string)}, "test:"))
+	defer s.Close()
+	challenge, err := s.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := pow.DecodeChallenge(challenge)
+	solution := c.Solve()
+	if good, err := s.Verify(challenge, solution); err != nil || !good {
+		t.Errorf("Verify = %v, %v; want true, nil", good, err)
+	}
+	if _, err := s.Verify(challenge, solution); !errors.Is(err, pow.ErrUnknownChallenge) {
+		t.Errorf("second Verify error = %v, want ErrUnknownChallenge", err)
+	}
+}
+
+func TestReplayStore(t *testing.T) {
+	f := &fakeRedis{keys: make(map[string]string)}
+	store := New(f, "test:")
+	c := pow.GenerateChallenge(2)
+	solution := c.Solve()
+	if good, err := c.CheckOnce(solution, store, time.Minute); err != nil || !good {
+		t.Errorf("CheckOnce = %v, %v; want true, nil", good, err)
+	}
+	if _, err := c.CheckOnce(solution, store, time.Minute); !errors.Is(err, pow.ErrReplayed) {
+		t.Errorf("second CheckOnce error = %v, want ErrReplayed", err)
+	}
+
+	// An unreachable Redis rejects rather than accepts
+	f.down = true
+	d := pow.GenerateChallenge(2)
+	good, err := d.CheckOnce(d.Solve(), store, time.Minute)
+	if good || !errors.Is(err, pow.ErrReplayStore) || errors.Is(err, pow.ErrReplayed) {
+		t.Errorf("CheckOnce while Redis was down = %v, %v; want ErrReplayStore", good, err)
+	}
+	if r := pow.ReasonOf(good, err); r == pow.ReasonReplayed {
+		t.Errorf("ReasonOf(outage) = %v", r)
+	}
+	if err := store.Mark("k", time.Minute); err == nil {
+		t.Error("Mark while Redis was down returned nil")
+	}
+}
@@ -0,0 +1,129 @@
+// Package powverify checks redpwnpow solutions using only math/big. It does
+// not depend on gmp or cgo, and links none of the solver, so verify-only
+// services can drop both entirely. It only knows the default field 2^1279-1.
+//
+// It accepts the same solutions as pow.Challenge.Check with the package's
+// default settings: a plain solution y in canonical or non-canonical base64
+// is accepted for x if it unwinds to x or its negation. It does not
+// implement pow's options RejectTwinSolutions, RequireCanonicalSolutions and
+// RejectWeakChallenges, so it accepts what Check would reject with any of
+// them set, and it rejects checkpoint proofs, which Check verifies. Like
+// Check, it compares values in constant time.
+package powverify
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/redpwn/pow/internal/wire"
+)
+
+var (
+	mod = new(big.Int)
+	one = big.NewInt(1)
+)
+
+func init() {
+	mod.Lsh(one, 1279)
+	mod.Sub(mod, one)
+}
+
+// MaxCheckDifficulty is the largest difficulty CheckBig will verify; it
+// mirrors pow.MaxCheckDifficulty.
+var MaxCheckDifficulty uint32 = 1 << 20
+
+// ErrDifficultyTooHigh is returned by CheckBig for challenges whose
+// difficulty exceeds MaxCheckDifficulty.
+var ErrDifficultyTooHigh = errors.New("difficulty exceeds MaxCheckDifficulty")
+
+// ErrValueTooLarge is returned by CheckBig for solutions whose value is not
+// smaller than the modulus.
+var ErrValueTooLarge = errors.New("value not smaller than modulus")
+
+// Challenge is a decoded challenge. It offers the verifying half of
+// pow.Challenge, so that a service that only decodes and checks challenges
+// can switch between the two by changing an import.
+type Challenge struct {
+	d uint32
+	x *big.Int
+}
+
+// DecodeChallenge decodes a challenge of the default field, as produced by
+// pow.Challenge.String. It returns ErrDifficultyTooHigh for challenges whose
+// difficulty exceeds MaxCheckDifficulty and ErrValueTooLarge for values that
+// are not smaller than the modulus, like pow.DecodeChallenge.
+func DecodeChallenge(v string) (*Challenge, error) {
+	d, xBytes, err := wire.Default.ParseChallenge(v)
+	if err != nil {
+		return nil, err
+	}
+	if d > MaxCheckDifficulty {
+		return nil, ErrDifficultyTooHigh
+	}
+	x := new(big.Int).SetBytes(xBytes)
+	if x.Cmp(mod) >= 0 {
+		return nil, ErrValueTooLarge
+	}
+	return &Challenge{d: d, x: x}, nil
+}
+
+// Difficulty returns the number of iterations of the challenge.
+func (c *Challenge) Difficulty() uint32 {
+	return c.d
+}
+
+// Check verifies that solution is a correct solution proof for c.
+func (c *Challenge) Check(solution string) (bool, error) {
+	if c.d > MaxCheckDifficulty {
+		return false, ErrDifficultyTooHigh
+	}
+	yBytes, err := wire.Default.ParseSolution(solution)
+	if err != nil {
+		return false, fmt.Errorf("decode solution: %w", err)
+	}
+	y := new(big.Int).SetBytes(yBytes)
+	if y.Cmp(mod) >= 0 {
+		return false, fmt.Errorf("decode solution: %w", ErrValueTooLarge)
+	}
+	if c.d == 0 {
+		return equalAny(y, c.x), nil
+	}
+
+	// Apply the inverse transformation d times
+	t := new(big.Int)
+	for i := uint32(0); i < c.d; i++ {
+		y.Xor(y, one)
+		t.Mul(y, y)
+		y.Mod(t, mod)
+	}
+
+	return equalAny(c.x, y, new(big.Int).Sub(mod, y)), nil
+}
+
+// modBytes is the width values are compared at.
+const modBytes = (1279 + 7) / 8
+
+// equalAny reports whether x equals any of ys, all in [0, mod], comparing
+// every one of them as fixed-width bytes in constant time.
+func equalAny(x *big.Int, ys ...*big.Int) bool {
+	var xb, yb [modBytes]byte
+	x.FillBytes(xb[:])
+	eq := 0
+	for _, y := range ys {
+		y.FillBytes(yb[:])
+		eq |= subtle.ConstantTimeCompare(xb[:], yb[:])
+	}
+	return eq == 1
+}
+
+// CheckBig verifies that solution is a correct solution proof for the encoded
+// challenge.
+func CheckBig(challenge, solution string) (bool, error) {
+	c, err := DecodeChallenge(challenge)
+	if err != nil {
+		return false, fmt.Errorf("decode challenge: %w", err)
+	}
+	return c.Check(solution)
+}
@@ -0,0 +1,47 @@
+package powverify
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestKCTFVectors checks the vectors from kCTF's reference pow.py, as the
+// pow package's test of the same name does for Check.
+func TestKCTFVectors(t *testing.T) {
+	f, err := os.Open("../testdata/kctf_vectors.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	n := 0
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 2 {
+			t.Fatalf("malformed vector line %q", sc.Text())
+		}
+		challenge, solution := fields[0], fields[1]
+		if good, err := CheckBig(challenge, solution); err != nil || !good {
+			t.Errorf("CheckBig(%s, %s) = %v, %v; want true, nil", challenge, solution, good, err)
+		}
+		c, err := DecodeChallenge(challenge)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.d > 0 {
+			if good, err := c.Check("s.AAAC"); err != nil || good {
+				t.Errorf("Check(%s, s.AAAC) = %v, %v; want false, nil", challenge, good, err)
+			}
+		}
+		n++
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatal("no vectors")
+	}
+}
@@ -0,0 +1,122 @@
+package pow
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// A Reason classifies the outcome of verifying a solution, so that
+// operators can tell attacks, such as replays and forged challenges, from
+// client bugs, such as malformed encodings.
+type Reason int
+
+const (
+	// ReasonAccepted means the solution was accepted.
+	ReasonAccepted Reason = iota
+	// ReasonMismatch means the solution was well-formed but wrong.
+	ReasonMismatch
+	// ReasonBadVersion means a version prefix was unknown or did not fit
+	// the challenge.
+	ReasonBadVersion
+	// ReasonBadEncoding means malformed input, such as invalid base64, the
+	// wrong number of segments, or a non-canonical encoding when one is
+	// required.
+	ReasonBadEncoding
+	// ReasonOutOfRange means a value was too long or not in the field.
+	ReasonOutOfRange
+	// ReasonBadDifficulty means the difficulty did not fit in 32 bits.
+	ReasonBadDifficulty
+	// ReasonDifficultyTooHigh means the difficulty exceeded the limit.
+	ReasonDifficultyTooHigh
+	// ReasonWeakChallenge means the challenge was weak and
+	// RejectWeakChallenges is set.
+	ReasonWeakChallenge
+	// ReasonBadSignature means a signed challenge was not issued with the
+	// key, or not for the context, it was verified with.
+	ReasonBadSignature
+	// ReasonExpired means a signed challenge had expired.
+	ReasonExpired
+	// ReasonUnknownChallenge means a Server had not issued the challenge,
+	// or it had expired or was already solved.
+	ReasonUnknownChallenge
+	// ReasonReplayed means CheckOnce had already accepted the challenge.
+	ReasonReplayed
+	// ReasonOverloaded means a Verifier shed the check.
+	ReasonOverloaded
+	// ReasonCanceled means the context ended before the check did.
+	ReasonCanceled
+	// ReasonOther is any other error, such as one from a store.
+	ReasonOther
+)
+
+var reasonNames = [...]string{
+	ReasonAccepted:          "accepted",
+	ReasonMismatch:          "mismatch",
+	ReasonBadVersion:        "bad_version",
+	ReasonBadEncoding:       "bad_encoding",
+	ReasonOutOfRange:        "out_of_range",
+	ReasonBadDifficulty:     "bad_difficulty",
+	ReasonDifficultyTooHigh: "difficulty_too_high",
+	ReasonWeakChallenge:     "weak_challenge",
+	ReasonBadSignature:      "bad_signature",
+	ReasonExpired:           "expired",
+	ReasonUnknownChallenge:  "unknown_challenge",
+	ReasonReplayed:          "replayed",
+	ReasonOverloaded:        "overloaded",
+	ReasonCanceled:          "canceled",
+	ReasonOther:             "other",
+}
+
+// String returns a short snake_case name for r, suitable as a metric label.
+func (r Reason) String() string {
+	if r >= 0 && int(r) < len(reasonNames) {
+		return reasonNames[r]
+	}
+	return "Reason(" + strconv.Itoa(int(r)) + ")"
+}
+
+// reasonErrors maps errors to reasons, most specific first.
+var reasonErrors = []struct {
+	err    error
+	reason Reason
+}{
+	{ErrBadVersion, ReasonBadVersion},
+	{ErrBadEncoding, ReasonBadEncoding},
+	{ErrValueOutOfRange, ReasonOutOfRange},
+	{ErrBadDifficulty, ReasonBadDifficulty},
+	{ErrDifficultyTooHigh, ReasonDifficultyTooHigh},
+	{ErrWeakChallenge, ReasonWeakChallenge},
+	{ErrBadSignature, ReasonBadSignature},
+	{ErrContextMismatch, ReasonBadSignature},
+	{ErrExpired, ReasonExpired},
+	{ErrUnknownChallenge, ReasonUnknownChallenge},
+	{ErrReplayed, ReasonReplayed},
+	{ErrOverloaded, ReasonOverloaded},
+	{context.Canceled, ReasonCanceled},
+	{context.DeadlineExceeded, ReasonCanceled},
+}
+
+// ReasonOf classifies the results of Check, or of any of the functions that
+// verify solutions.
+func ReasonOf(ok bool, err error) Reason {
+	if err == nil {
+		if ok {
+			return ReasonAccepted
+		}
+		return ReasonMismatch
+	}
+	for _, re := range reasonErrors {
+		if errors.Is(err, re.err) {
+			return re.reason
+		}
+	}
+	return ReasonOther
+}
+
+// CheckDetailed is like Check but returns the outcome as a CheckResult,
+// whose Reason says why a solution was rejected.
+func (c *Challenge) CheckDetailed(s string) CheckResult {
+	ok, err := c.Check(s)
+	return CheckResult{OK: ok, Err: err, Reason: ReasonOf(ok, err)}
+}
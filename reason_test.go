@@ -0,0 +1,57 @@
+package pow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCheckDetailed(t *testing.T) {
+	c := GenerateChallenge(3)
+	s := c.Solve()
+	for in, want := range map[string]Reason{
+		s:                               ReasonAccepted,
+		"s.AA==":                        ReasonMismatch,
+		"z.AA==":                        ReasonBadVersion,
+		"s.!!":                          ReasonBadEncoding,
+		ParamsP1279.encodeSolution(mod): ReasonOutOfRange,
+	} {
+		r := c.CheckDetailed(in)
+		if r.Reason != want || r.OK != (want == ReasonAccepted) {
+			t.Errorf("CheckDetailed(%.20q) = %+v, want reason %v", in, r, want)
+		}
+	}
+
+	hard := &Challenge{d: MaxCheckDifficulty + 1, x: NewInt(5)}
+	if r := hard.CheckDetailed(s); r.Reason != ReasonDifficultyTooHigh {
+		t.Errorf("CheckDetailed of hard challenge = %+v, want ReasonDifficultyTooHigh", r)
+	}
+}
+
+func TestReasonOf(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want Reason
+	}{
+		{ErrExpired, ReasonExpired},
+		{ErrBadSignature, ReasonBadSignature},
+		{ErrReplayed, ReasonReplayed},
+		{fmt.Errorf("verify: %w", ErrUnknownChallenge), ReasonUnknownChallenge},
+		{ErrOverloaded, ReasonOverloaded},
+		{context.DeadlineExceeded, ReasonCanceled},
+		{ErrNonCanonical, ReasonBadEncoding},
+		{ErrUnsupportedVersion, ReasonBadVersion},
+		{errors.New("redis down"), ReasonOther},
+	} {
+		if got := ReasonOf(false, tt.err); got != tt.want {
+			t.Errorf("ReasonOf(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+	if s := ReasonDifficultyTooHigh.String(); s != "difficulty_too_high" {
+		t.Errorf("String = %q", s)
+	}
+	if s := Reason(100).String(); s != "Reason(100)" {
+		t.Errorf("String of unknown reason = %q", s)
+	}
+}
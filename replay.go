@@ -0,0 +1,85 @@
+package pow
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrReplayed is returned by CheckOnce for challenges whose solution was
+// already accepted.
+var ErrReplayed = errors.New("challenge already solved")
+
+// ErrReplayStore is matched by the errors CheckOnce returns when its store
+// fails. They also match the store's own error, and are never ErrReplayed,
+// so that an outage is not mistaken for an attack.
+var ErrReplayStore = errors.New("replay store failed")
+
+// replayStoreError wraps an error from a ReplayStore.
+type replayStoreError struct {
+	err error
+}
+
+func (e *replayStoreError) Error() string        { return "replay store: " + e.err.Error() }
+func (e *replayStoreError) Unwrap() error        { return e.err }
+func (e *replayStoreError) Is(target error) bool { return target == ErrReplayStore }
+
+// A ReplayStore remembers which challenges have been solved, so that
+// CheckOnce accepts each at most once. Keys are canonical challenge
+// encodings. Implementations must be safe for concurrent use.
+//
+// Seen followed by Mark is not atomic, so two concurrent submissions for the
+// same challenge may both be accepted. Stores that can close that gap should
+// also implement ReplayClaimer. Stores that cannot fail return nil errors.
+type ReplayStore interface {
+	// Seen reports whether key was marked and has not expired.
+	Seen(key string) (bool, error)
+	// Mark records key as seen for ttl.
+	Mark(key string, ttl time.Duration) error
+}
+
+// A ReplayClaimer is a ReplayStore that can check and mark a key in one
+// step. CheckOnce uses Claim instead of Mark when a store provides it.
+type ReplayClaimer interface {
+	ReplayStore
+	// Claim marks key as seen for ttl, reporting whether it was unseen. Of
+	// several concurrent calls for the same key at most one may report
+	// true.
+	Claim(key string, ttl time.Duration) (bool, error)
+}
+
+// CheckOnce is like Check but consumes the challenge: once a solution has
+// been accepted, store remembers the challenge for ttl and later calls
+// return ErrReplayed. The challenge rather than the solution is recorded,
+// since both x and its negation are accepted. ttl should cover however long
+// the challenge may be presented, for instance a signed challenge's expiry.
+// If the store fails, CheckOnce rejects the solution with an error matching
+// ErrReplayStore.
+func (c *Challenge) CheckOnce(s string, store ReplayStore, ttl time.Duration) (bool, error) {
+	key := c.String()
+	// Reject known replays before paying for the check
+	seen, err := store.Seen(key)
+	if err != nil {
+		return false, &replayStoreError{err}
+	}
+	if seen {
+		return false, ErrReplayed
+	}
+	good, err := c.Check(s)
+	if err != nil || !good {
+		return false, err
+	}
+	if rc, ok := store.(ReplayClaimer); ok {
+		claimed, err := rc.Claim(key, ttl)
+		if err != nil {
+			return false, &replayStoreError{err}
+		}
+		if !claimed {
+			return false, ErrReplayed
+		}
+		return true, nil
+	}
+	if err := store.Mark(key, ttl); err != nil {
+		return false, &replayStoreError{err}
+	}
+	return true, nil
+}
@@ -0,0 +1,156 @@
+package pow
+
+import (
+	"hash/maphash"
+	"math"
+	"sync"
+	"time"
+)
+
+// BloomReplayStore is a ReplayClaimer that records keys in Bloom filters
+// instead of storing them, for request volumes where remembering every
+// solved challenge is too costly. Memory is fixed when the store is created.
+//
+// Keys go into the current of two filters, and every window the older filter
+// is discarded and a new one started. A key is therefore remembered for
+// between one and two windows, whatever ttl it is marked with, and window
+// should be at least the longest ttl used.
+//
+// Bloom filters have false positives but no false negatives: a replay is
+// never accepted, but a first solution is reported as seen, and rejected by
+// CheckOnce, with about the configured probability. A key is looked up in
+// both filters, so each is sized for half of it.
+type BloomReplayStore struct {
+	window time.Duration
+	k      int
+	seeds  [2]maphash.Seed
+
+	mu       sync.RWMutex
+	cur      []uint64
+	prev     []uint64
+	rotateAt time.Time
+}
+
+// NewBloomReplayStore returns an empty BloomReplayStore sized for n keys per
+// window with a false positive rate of fpRate when both filters are full.
+func NewBloomReplayStore(n int, fpRate float64, window time.Duration) *BloomReplayStore {
+	if n < 1 {
+		n = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+	// The optimal filter has m = -n ln p / (ln 2)^2 bits and k = m/n ln 2
+	// hash functions. A lookup misfires if either filter does, so each
+	// gets p = fpRate/2.
+	p := fpRate / 2
+	m := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	k := int(math.Round(m / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	words := (int(m) + 63) / 64
+	return &BloomReplayStore{
+		window:   window,
+		k:        k,
+		seeds:    [2]maphash.Seed{maphash.MakeSeed(), maphash.MakeSeed()},
+		cur:      make([]uint64, words),
+		prev:     make([]uint64, words),
+		rotateAt: now().Add(window),
+	}
+}
+
+// Seen implements ReplayStore.
+func (b *BloomReplayStore) Seen(key string) (bool, error) {
+	h1, h2 := b.hash(key)
+	b.mu.RLock()
+	stale := !now().Before(b.rotateAt)
+	seen := b.test(b.cur, h1, h2) || b.test(b.prev, h1, h2)
+	b.mu.RUnlock()
+	if stale {
+		// Rotate and look again, the filters may have been outdated.
+		b.mu.Lock()
+		b.rotate()
+		seen = b.test(b.cur, h1, h2) || b.test(b.prev, h1, h2)
+		b.mu.Unlock()
+	}
+	return seen, nil
+}
+
+// Mark implements ReplayStore. ttl is not used; see BloomReplayStore.
+func (b *BloomReplayStore) Mark(key string, ttl time.Duration) error {
+	_, err := b.Claim(key, ttl)
+	return err
+}
+
+// Claim implements ReplayClaimer. ttl is not used; see BloomReplayStore.
+func (b *BloomReplayStore) Claim(key string, ttl time.Duration) (bool, error) {
+	h1, h2 := b.hash(key)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rotate()
+	if b.test(b.prev, h1, h2) {
+		return false, nil
+	}
+	return b.set(b.cur, h1, h2), nil
+}
+
+// rotate discards the filters that are more than a window old.
+func (b *BloomReplayStore) rotate() {
+	t := now()
+	if t.Before(b.rotateAt) {
+		return
+	}
+	if t.Before(b.rotateAt.Add(b.window)) {
+		b.cur, b.prev = b.prev, b.cur
+		clearBits(b.cur)
+	} else {
+		clearBits(b.cur)
+		clearBits(b.prev)
+	}
+	b.rotateAt = t.Add(b.window)
+}
+
+func (b *BloomReplayStore) hash(key string) (uint64, uint64) {
+	var h maphash.Hash
+	h.SetSeed(b.seeds[0])
+	h.WriteString(key)
+	h1 := h.Sum64()
+	h.SetSeed(b.seeds[1])
+	h.WriteString(key)
+	// An odd step visits distinct bits whatever the filter size.
+	return h1, h.Sum64() | 1
+}
+
+// test reports whether all of the key's bits are set in f. The i-th bit is
+// h1 + i*h2, following Kirsch and Mitzenmacher.
+func (b *BloomReplayStore) test(f []uint64, h1, h2 uint64) bool {
+	m := uint64(len(f)) * 64
+	for i := 0; i < b.k; i++ {
+		j := (h1 + uint64(i)*h2) % m
+		if f[j/64]&(1<<(j%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// set sets the key's bits in f, reporting whether any was unset.
+func (b *BloomReplayStore) set(f []uint64, h1, h2 uint64) bool {
+	m := uint64(len(f)) * 64
+	added := false
+	for i := 0; i < b.k; i++ {
+		j := (h1 + uint64(i)*h2) % m
+		if f[j/64]&(1<<(j%64)) == 0 {
+			f[j/64] |= 1 << (j % 64)
+			added = true
+		}
+	}
+	return added
+}
+
+func clearBits(f []uint64) {
+	for i := range f {
+		f[i] = 0
+	}
+}
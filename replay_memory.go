@@ -0,0 +1,147 @@
+package pow
+
+import (
+	"container/heap"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+const replayShards = 32
+
+// MemoryReplayStore is a ReplayClaimer that keeps keys in memory, for servers
+// running as a single instance. Keys are spread over independently locked
+// shards, each ordered by expiry so that expired keys are dropped as new ones
+// arrive without scanning.
+//
+// Memory is bounded by the size given to NewMemoryReplayStore. When a shard
+// is full of unexpired keys, the key closest to expiring is forgotten early,
+// which lets that challenge be replayed; size should comfortably exceed the
+// number of challenges solved within a ttl.
+type MemoryReplayStore struct {
+	seed   maphash.Seed
+	shards [replayShards]replayShard
+}
+
+type replayShard struct {
+	mu    sync.Mutex
+	max   int
+	keys  map[string]*replayEntry
+	queue replayQueue
+}
+
+type replayEntry struct {
+	key    string
+	expiry time.Time
+	index  int // in the shard's queue
+}
+
+// NewMemoryReplayStore returns an empty MemoryReplayStore holding about size
+// keys at most.
+func NewMemoryReplayStore(size int) *MemoryReplayStore {
+	per := (size + replayShards - 1) / replayShards
+	if per < 1 {
+		per = 1
+	}
+	m := &MemoryReplayStore{seed: maphash.MakeSeed()}
+	for i := range m.shards {
+		m.shards[i].max = per
+		m.shards[i].keys = make(map[string]*replayEntry)
+	}
+	return m
+}
+
+func (m *MemoryReplayStore) shard(key string) *replayShard {
+	var h maphash.Hash
+	h.SetSeed(m.seed)
+	h.WriteString(key)
+	return &m.shards[h.Sum64()%replayShards]
+}
+
+// Seen implements ReplayStore.
+func (m *MemoryReplayStore) Seen(key string) (bool, error) {
+	sh := m.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	e, ok := sh.keys[key]
+	return ok && now().Before(e.expiry), nil
+}
+
+// Mark implements ReplayStore.
+func (m *MemoryReplayStore) Mark(key string, ttl time.Duration) error {
+	sh := m.shard(key)
+	sh.mu.Lock()
+	sh.mark(key, now().Add(ttl), true)
+	sh.mu.Unlock()
+	return nil
+}
+
+// Claim implements ReplayClaimer.
+func (m *MemoryReplayStore) Claim(key string, ttl time.Duration) (bool, error) {
+	sh := m.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.mark(key, now().Add(ttl), false), nil
+}
+
+// Len returns the number of keys held, including expired ones that have not
+// been dropped yet.
+func (m *MemoryReplayStore) Len() int {
+	n := 0
+	for i := range m.shards {
+		sh := &m.shards[i]
+		sh.mu.Lock()
+		n += len(sh.keys)
+		sh.mu.Unlock()
+	}
+	return n
+}
+
+// mark records key until expiry, reporting whether it was unseen. An
+// unexpired key is only given the new expiry if extend is set.
+func (sh *replayShard) mark(key string, expiry time.Time, extend bool) bool {
+	t := now()
+	for len(sh.queue) > 0 && !t.Before(sh.queue[0].expiry) {
+		delete(sh.keys, heap.Pop(&sh.queue).(*replayEntry).key)
+	}
+	if e, ok := sh.keys[key]; ok {
+		if extend && expiry.After(e.expiry) {
+			e.expiry = expiry
+			heap.Fix(&sh.queue, e.index)
+		}
+		return false
+	}
+	if len(sh.queue) >= sh.max {
+		delete(sh.keys, heap.Pop(&sh.queue).(*replayEntry).key)
+	}
+	e := &replayEntry{key: key, expiry: expiry}
+	heap.Push(&sh.queue, e)
+	sh.keys[key] = e
+	return true
+}
+
+// replayQueue is a heap of entries, soonest to expire first.
+type replayQueue []*replayEntry
+
+func (q replayQueue) Len() int           { return len(q) }
+func (q replayQueue) Less(i, j int) bool { return q[i].expiry.Before(q[j].expiry) }
+
+func (q replayQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *replayQueue) Push(x interface{}) {
+	e := x.(*replayEntry)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *replayQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return e
+}
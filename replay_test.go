@@ -0,0 +1,205 @@
+package pow
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mapReplayStore is a minimal ReplayStore that ignores ttl.
+type mapReplayStore struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func (m *mapReplayStore) Seen(key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.seen[key], nil
+}
+
+func (m *mapReplayStore) Mark(key string, ttl time.Duration) error {
+	m.mu.Lock()
+	m.seen[key] = true
+	m.mu.Unlock()
+	return nil
+}
+
+// failingReplayStore is a ReplayStore whose backend is unreachable.
+type failingReplayStore struct{}
+
+var errStoreDown = errors.New("store down")
+
+func (failingReplayStore) Seen(key string) (bool, error)            { return false, errStoreDown }
+func (failingReplayStore) Mark(key string, ttl time.Duration) error { return errStoreDown }
+
+func TestCheckOnce(t *testing.T) {
+	store := &mapReplayStore{seen: make(map[string]bool)}
+	c := GenerateChallenge(3)
+	s := c.Solve()
+
+	if good, err := c.CheckOnce("s.AA==", store, time.Minute); err != nil || good {
+		t.Errorf("CheckOnce with wrong solution = %v, %v; want false, nil", good, err)
+	}
+	if seen, _ := store.Seen(c.String()); seen {
+		t.Error("rejected solution consumed the challenge")
+	}
+	if good, err := c.CheckOnce(s, store, time.Minute); err != nil || !good {
+		t.Errorf("CheckOnce = %v, %v; want true, nil", good, err)
+	}
+
+	// Neither the same solution, its negation, nor another encoding of the
+	// challenge gets through again
+	d, err := DecodeChallenge(c.StringURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := c.params()
+	y, _ := p.decodeSolution(s)
+	neg := p.encodeSolution(NewInt(0).Sub(p.Modulus, y))
+	for _, s := range []string{s, neg} {
+		if _, err := d.CheckOnce(s, store, time.Minute); !errors.Is(err, ErrReplayed) {
+			t.Errorf("replayed CheckOnce error = %v, want ErrReplayed", err)
+		}
+	}
+}
+
+func TestCheckOnceStoreError(t *testing.T) {
+	c := GenerateChallenge(3)
+	good, err := c.CheckOnce(c.Solve(), failingReplayStore{}, time.Minute)
+	if good || !errors.Is(err, ErrReplayStore) || !errors.Is(err, errStoreDown) || errors.Is(err, ErrReplayed) {
+		t.Errorf("CheckOnce with a failing store = %v, %v; want ErrReplayStore", good, err)
+	}
+	if r := ReasonOf(good, err); r != ReasonOther {
+		t.Errorf("ReasonOf(store error) = %v, want %v", r, ReasonOther)
+	}
+}
+
+// seenKey and claimKey call Seen and Claim on the in-memory stores, which
+// never fail.
+func seenKey(s ReplayStore, key string) bool {
+	seen, _ := s.Seen(key)
+	return seen
+}
+
+func claimKey(s ReplayClaimer, key string, ttl time.Duration) bool {
+	claimed, _ := s.Claim(key, ttl)
+	return claimed
+}
+
+func TestMemoryReplayStore(t *testing.T) {
+	defer func() { now = time.Now }()
+	start := time.Now()
+	now = func() time.Time { return start }
+
+	// Room for a and b even if they share a shard
+	m := NewMemoryReplayStore(2 * replayShards)
+	if seenKey(m, "a") {
+		t.Error("empty store has seen a key")
+	}
+	if !claimKey(m, "a", time.Minute) {
+		t.Error("first Claim = false")
+	}
+	if claimKey(m, "a", time.Minute) {
+		t.Error("second Claim = true")
+	}
+	m.Mark("b", 2*time.Minute)
+	if !seenKey(m, "a") || !seenKey(m, "b") {
+		t.Error("marked keys not seen")
+	}
+
+	// Keys are forgotten once their ttl passes, and dropped as new keys
+	// arrive in their shard
+	now = func() time.Time { return start.Add(90 * time.Second) }
+	if seenKey(m, "a") || !seenKey(m, "b") {
+		t.Error("keys not expired by ttl")
+	}
+	if !claimKey(m, "a", time.Minute) {
+		t.Error("Claim of expired key = false")
+	}
+
+	// Memory is bounded: a full shard forgets the key closest to expiring
+	for i := 0; i < 200; i++ {
+		m.Mark(string(rune('A'+i)), time.Hour)
+	}
+	if n := m.Len(); n > 2*replayShards {
+		t.Errorf("Len = %d, want at most %d", n, 2*replayShards)
+	}
+}
+
+func TestMemoryReplayStoreConcurrentClaim(t *testing.T) {
+	m := NewMemoryReplayStore(1000)
+	c := GenerateChallenge(2)
+	s := c.Solve()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if good, _ := c.CheckOnce(s, m, time.Minute); good {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted != 1 {
+		t.Errorf("%d concurrent CheckOnce calls accepted, want 1", accepted)
+	}
+}
+
+func TestBloomReplayStore(t *testing.T) {
+	defer func() { now = time.Now }()
+	start := time.Now()
+	now = func() time.Time { return start }
+
+	b := NewBloomReplayStore(1000, 0.01, time.Minute)
+	if !claimKey(b, "a", time.Minute) {
+		t.Error("first Claim = false")
+	}
+	if claimKey(b, "a", time.Minute) || !seenKey(b, "a") {
+		t.Error("claimed key not seen")
+	}
+
+	// A key survives one rotation and is forgotten after the second
+	now = func() time.Time { return start.Add(90 * time.Second) }
+	if !seenKey(b, "a") {
+		t.Error("key forgotten after one window")
+	}
+	b.Mark("b", time.Minute)
+	now = func() time.Time { return start.Add(150 * time.Second) }
+	if seenKey(b, "a") {
+		t.Error("key remembered after two windows")
+	}
+	if !seenKey(b, "b") {
+		t.Error("key marked in the previous window forgotten")
+	}
+
+	// Keys are never missed, and with both filters full false positives
+	// stay near the target rate
+	for i := 0; i < 1000; i++ {
+		b.Mark(fmt.Sprint("k", i), time.Minute)
+	}
+	now = func() time.Time { return start.Add(240 * time.Second) }
+	for i := 1000; i < 2000; i++ {
+		b.Mark(fmt.Sprint("k", i), time.Minute)
+	}
+	fp := 0
+	for i := 0; i < 2000; i++ {
+		if !seenKey(b, fmt.Sprint("k", i)) {
+			t.Fatalf("marked key k%d not seen", i)
+		}
+		if seenKey(b, fmt.Sprint("x", i)) {
+			fp++
+		}
+	}
+	if fp > 45 {
+		t.Errorf("%d false positives in 2000, want about 20", fp)
+	}
+}
@@ -0,0 +1,117 @@
+package pow
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/redpwn/pow/internal/wire"
+)
+
+// A Puzzle is a decoded challenge of some Scheme. *Challenge is the Puzzle of
+// the built-in schemes.
+type Puzzle interface {
+	// String encodes the challenge, beginning with its scheme's version.
+	String() string
+	// Solve solves the challenge and returns the encoded solution.
+	Solve() string
+	// Check verifies an encoded solution.
+	Check(solution string) (bool, error)
+}
+
+// A Scheme is a proof-of-work flavor, identified by the version prefix of its
+// encoded challenges. *Params is the Scheme of the built-in fields.
+// Implementations must be safe for concurrent use.
+type Scheme interface {
+	// Generate creates a new random challenge of difficulty d.
+	Generate(d uint32) (Puzzle, error)
+	// Decode decodes an encoded challenge of this scheme.
+	Decode(challenge string) (Puzzle, error)
+}
+
+// ErrSchemeExists is returned by RegisterScheme for versions that are
+// already registered.
+var ErrSchemeExists = errors.New("scheme already registered")
+
+var schemes = struct {
+	sync.RWMutex
+	m map[string]Scheme
+}{m: map[string]Scheme{
+	ParamsP1279.Version: ParamsP1279,
+	ParamsP2203.Version: ParamsP2203,
+}}
+
+// RegisterScheme makes s available under version to DecodePuzzle and
+// LookupScheme. Registering a *Params, whose Version must equal version, also
+// lets DecodeChallenge and the other decoders recognize its challenges. It
+// returns ErrSchemeExists if version is taken and ErrInvalidParams if version
+// is not usable.
+func RegisterScheme(version string, s Scheme) error {
+	if !validVersion(version) {
+		return fmt.Errorf("%w: version %q", ErrInvalidParams, version)
+	}
+	if p, ok := s.(*Params); ok {
+		if p.Version != version {
+			return fmt.Errorf("%w: version %q registered as %q", ErrInvalidParams, p.Version, version)
+		}
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+	schemes.Lock()
+	defer schemes.Unlock()
+	if _, ok := schemes.m[version]; ok {
+		return fmt.Errorf("%w: %q", ErrSchemeExists, version)
+	}
+	schemes.m[version] = s
+	return nil
+}
+
+// LookupScheme returns the scheme registered under version.
+func LookupScheme(version string) (Scheme, bool) {
+	schemes.RLock()
+	s, ok := schemes.m[version]
+	schemes.RUnlock()
+	return s, ok
+}
+
+// DecodePuzzle decodes a challenge of any registered scheme, chosen by its
+// version prefix.
+func DecodePuzzle(challenge string) (Puzzle, error) {
+	version := wire.Prefix(challenge)
+	s, ok := LookupScheme(version)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrBadVersion, version)
+	}
+	return s.Decode(challenge)
+}
+
+// paramsForVersion returns the parameters registered under version, if the
+// scheme registered there is a *Params.
+func paramsForVersion(version string) (*Params, bool) {
+	s, _ := LookupScheme(version)
+	p, ok := s.(*Params)
+	return p, ok
+}
+
+// Generate implements Scheme.
+func (p *Params) Generate(d uint32) (Puzzle, error) {
+	return p.GenerateChallenge(d), nil
+}
+
+// Decode implements Scheme. It is like DecodeChallenge but only accepts
+// challenges with p's version.
+func (p *Params) Decode(challenge string) (Puzzle, error) {
+	d, xBytes, err := p.format().ParseChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.newChallenge(d, xBytes, MaxCheckDifficulty)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireCanonical(challenge, c.String()); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,102 @@
+package pow
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// echoScheme is a toy scheme whose solution is the challenge reversed.
+type echoScheme struct{}
+
+type echoPuzzle string
+
+func (echoScheme) Generate(d uint32) (Puzzle, error) {
+	return echoPuzzle("echo." + strings.Repeat("x", int(d))), nil
+}
+
+func (echoScheme) Decode(challenge string) (Puzzle, error) {
+	if !strings.HasPrefix(challenge, "echo.") {
+		return nil, ErrBadVersion
+	}
+	return echoPuzzle(challenge), nil
+}
+
+func (p echoPuzzle) String() string { return string(p) }
+
+func (p echoPuzzle) Solve() string {
+	b := []byte(p)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+func (p echoPuzzle) Check(solution string) (bool, error) {
+	return solution == p.Solve(), nil
+}
+
+func TestRegisterScheme(t *testing.T) {
+	if err := RegisterScheme("echo", echoScheme{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterScheme("echo", echoScheme{}); !errors.Is(err, ErrSchemeExists) {
+		t.Errorf("registering echo twice: err = %v, want %v", err, ErrSchemeExists)
+	}
+	p, err := DecodePuzzle("echo.abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := p.Check("cba.ohce"); !ok || err != nil {
+		t.Errorf("Check = %v, %v; want true, nil", ok, err)
+	}
+	if _, err := DecodeChallenge("echo.abc"); !errors.Is(err, ErrBadVersion) {
+		t.Errorf("DecodeChallenge of an echo challenge: err = %v, want %v", err, ErrBadVersion)
+	}
+
+	// built-in schemes go through the same registry
+	c := GenerateChallenge(3)
+	p, err = DecodePuzzle(c.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := p.Check(c.Solve()); !ok || err != nil {
+		t.Errorf("Check of a decoded built-in puzzle = %v, %v; want true, nil", ok, err)
+	}
+	if _, err := DecodePuzzle("nope.AA=="); !errors.Is(err, ErrBadVersion) {
+		t.Errorf("DecodePuzzle with unknown version: err = %v, want %v", err, ErrBadVersion)
+	}
+	if _, err := ParamsP1279.Decode("s.AAAAAQ==.!!"); err == nil {
+		t.Error("Params.Decode accepted a malformed challenge")
+	}
+}
+
+func TestRegisterParams(t *testing.T) {
+	p, err := NewMersenneParams("s521", 521)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := p.GenerateChallenge(4).String()
+	if _, err := DecodeChallenge(s); !errors.Is(err, ErrBadVersion) {
+		t.Errorf("DecodeChallenge before registering: err = %v, want %v", err, ErrBadVersion)
+	}
+	if err := RegisterScheme("other", p); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("registering params under another version: err = %v, want %v", err, ErrInvalidParams)
+	}
+	if err := RegisterScheme(p.Version, p); err != nil {
+		t.Fatal(err)
+	}
+	c, err := DecodeChallenge(s)
+	if err != nil {
+		t.Fatalf("DecodeChallenge after registering: %v", err)
+	}
+	if c.params() != p {
+		t.Error("decoded challenge does not use the registered params")
+	}
+	if ok, err := c.Check(c.Solve()); !ok || err != nil {
+		t.Errorf("Check = %v, %v; want true, nil", ok, err)
+	}
+	if err := RegisterScheme("a.b", echoScheme{}); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("registering a version with a dot: err = %v, want %v", err, ErrInvalidParams)
+	}
+}
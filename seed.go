@@ -0,0 +1,50 @@
+package pow
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// seedDomain separates the hashes of ChallengeFromSeed from other uses of
+// SHA-256 with the same inputs.
+const seedDomain = "redpwnpow seed v1"
+
+// ChallengeFromSeed derives a challenge of difficulty d deterministically
+// from seed, so that the same seed always yields the same challenge. A
+// stateless server can hash what it knows about a request, such as the
+// client address, the path and a server secret, into the seed, and recompute
+// the challenge when the solution arrives instead of storing it.
+//
+// The value is the first of
+//
+//	SHA-256(seedDomain || 0x00 || version || 0x00 || d || i || seed)
+//
+// for i = 0, 1, ... that is not weak (see IsWeak), where version is the wire
+// version prefix, d and i are four bytes big-endian, and the hash is read as
+// a big-endian integer reduced modulo the field.
+func ChallengeFromSeed(seed []byte, d uint32) *Challenge {
+	return ParamsP1279.ChallengeFromSeed(seed, d)
+}
+
+// ChallengeFromSeed is like the package-level ChallengeFromSeed for the field
+// described by p.
+func (p *Params) ChallengeFromSeed(seed []byte, d uint32) *Challenge {
+	c := &Challenge{d: d, x: NewInt(0), p: p}
+	var buf [8]byte
+	binary.BigEndian.PutUint32(buf[:4], d)
+	for i := uint32(0); ; i++ {
+		binary.BigEndian.PutUint32(buf[4:], i)
+		h := sha256.New()
+		h.Write([]byte(seedDomain))
+		h.Write([]byte{0})
+		h.Write([]byte(p.Version))
+		h.Write([]byte{0})
+		h.Write(buf[:])
+		h.Write(seed)
+		c.x.SetBytes(h.Sum(nil))
+		c.x.Mod(c.x, p.Modulus)
+		if !c.IsWeak() {
+			return c
+		}
+	}
+}
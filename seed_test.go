@@ -0,0 +1,40 @@
+package pow
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+func TestChallengeFromSeed(t *testing.T) {
+	seed := []byte("203.0.113.7 /login secret")
+	c := ChallengeFromSeed(seed, 50)
+	if again := ChallengeFromSeed(seed, 50); again.String() != c.String() {
+		t.Errorf("ChallengeFromSeed is not deterministic: %s then %s", c, again)
+	}
+	if c.d != 50 || c.Valid() != nil {
+		t.Errorf("ChallengeFromSeed = %s, Valid() = %v", c, c.Valid())
+	}
+
+	// the construction documented on ChallengeFromSeed
+	want := sha256.Sum256([]byte("redpwnpow seed v1\x00s\x00\x00\x00\x00\x32\x00\x00\x00\x00" + string(seed)))
+	if got := fmt.Sprintf("%x", c.x.Bytes()); got != fmt.Sprintf("%x", want) {
+		t.Errorf("value = %s, want %x", got, want)
+	}
+
+	for _, other := range []*Challenge{
+		ChallengeFromSeed(seed[1:], 50),
+		ChallengeFromSeed(seed, 51),
+		ParamsP2203.ChallengeFromSeed(seed, 50),
+	} {
+		if other.value().Cmp(c.value()) == 0 {
+			t.Errorf("%s has the same value as %s", other, c)
+		}
+	}
+
+	solution := c.Solve()
+	recomputed := ChallengeFromSeed(seed, 50)
+	if ok, err := recomputed.Check(solution); !ok || err != nil {
+		t.Errorf("Check against recomputed challenge = %v, %v; want true, nil", ok, err)
+	}
+}
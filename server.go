@@ -0,0 +1,190 @@
+package pow
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrUnknownChallenge is returned by Server.Verify for challenges that were
+// not issued by the server, have expired, or were already solved.
+var ErrUnknownChallenge = errors.New("unknown challenge")
+
+// A ChallengeStore records the challenges a Server has issued and not yet
+// seen solved. Implementations must be safe for concurrent use.
+type ChallengeStore interface {
+	// Add records challenge as outstanding until expiry.
+	Add(challenge string, expiry time.Time) error
+	// Contains reports whether challenge is outstanding and unexpired.
+	Contains(challenge string) (bool, error)
+	// Remove deletes challenge, reporting whether it was outstanding and
+	// unexpired. Of several concurrent calls for the same challenge at
+	// most one may report true.
+	Remove(challenge string) (bool, error)
+	// Sweep deletes challenges that expired before now. Stores that expire
+	// entries by themselves may do nothing.
+	Sweep(now time.Time) error
+}
+
+// MemoryStore is a ChallengeStore that keeps challenges in memory.
+type MemoryStore struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{expires: make(map[string]time.Time)}
+}
+
+// Add implements ChallengeStore.
+func (m *MemoryStore) Add(challenge string, expiry time.Time) error {
+	m.mu.Lock()
+	m.expires[challenge] = expiry
+	m.mu.Unlock()
+	return nil
+}
+
+// Contains implements ChallengeStore.
+func (m *MemoryStore) Contains(challenge string) (bool, error) {
+	m.mu.Lock()
+	expiry, ok := m.expires[challenge]
+	m.mu.Unlock()
+	return ok && !now().After(expiry), nil
+}
+
+// Remove implements ChallengeStore.
+func (m *MemoryStore) Remove(challenge string) (bool, error) {
+	m.mu.Lock()
+	expiry, ok := m.expires[challenge]
+	delete(m.expires, challenge)
+	m.mu.Unlock()
+	return ok && !now().After(expiry), nil
+}
+
+// Sweep implements ChallengeStore.
+func (m *MemoryStore) Sweep(t time.Time) error {
+	m.mu.Lock()
+	for challenge, expiry := range m.expires {
+		if t.After(expiry) {
+			delete(m.expires, challenge)
+		}
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// Server issues challenges and verifies their solutions, accepting each
+// challenge at most once and only until it expires. It is safe for
+// concurrent use.
+type Server struct {
+	// Auditor, if not nil, receives an event for every challenge issued
+	// and every solution verified. Set it before using the server.
+	Auditor Auditor
+	// Difficulty, if not nil, chooses the difficulty of each challenge
+	// Issue creates in place of the one given to NewServer, for instance
+	// DifficultyController.Difficulty.
+	Difficulty func() uint32
+
+	d     uint32
+	ttl   time.Duration
+	store ChallengeStore
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewServer returns a Server issuing challenges of difficulty d that expire
+// after ttl. If store is nil, a new MemoryStore is used. The server sweeps
+// expired challenges from the store in the background until Close is
+// called.
+func NewServer(d uint32, ttl time.Duration, store ChallengeStore) *Server {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	s := &Server{
+		d:     d,
+		ttl:   ttl,
+		store: store,
+		done:  make(chan struct{}),
+	}
+	go s.sweep()
+	return s
+}
+
+func (s *Server) sweep() {
+	interval := s.ttl
+	if interval < time.Second {
+		interval = time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-t.C:
+			s.store.Sweep(now())
+		}
+	}
+}
+
+// Issue creates a new challenge, records it as outstanding, and returns its
+// encoding.
+func (s *Server) Issue() (string, error) {
+	d := s.d
+	if s.Difficulty != nil {
+		d = s.Difficulty()
+	}
+	c := GenerateChallenge(d).String()
+	if err := s.store.Add(c, now().Add(s.ttl)); err != nil {
+		return "", err
+	}
+	if s.Auditor != nil {
+		s.Auditor.Audit(AuditEvent{Type: ChallengeIssued, Time: now(), Challenge: c, Difficulty: d})
+	}
+	return c, nil
+}
+
+// Verify checks solution against challenge. It returns ErrUnknownChallenge
+// if the challenge is not outstanding. A challenge is consumed once it has
+// been solved, so later calls for it return ErrUnknownChallenge.
+func (s *Server) Verify(challenge, solution string) (bool, error) {
+	ok, d, err := s.verify(challenge, solution)
+	auditCheck(s.Auditor, challenge, d, ok, err)
+	return ok, err
+}
+
+// verify implements Verify, also returning the challenge's difficulty if it
+// got as far as decoding it.
+func (s *Server) verify(challenge, solution string) (bool, uint32, error) {
+	ok, err := s.store.Contains(challenge)
+	if err != nil {
+		return false, 0, err
+	}
+	if !ok {
+		return false, 0, ErrUnknownChallenge
+	}
+	c, err := DecodeChallenge(challenge)
+	if err != nil {
+		return false, 0, err
+	}
+	good, err := c.Check(solution)
+	if err != nil || !good {
+		return false, c.d, err
+	}
+	// another caller may have solved it in the meantime
+	if ok, err := s.store.Remove(challenge); err != nil || !ok {
+		if err == nil {
+			err = ErrUnknownChallenge
+		}
+		return false, c.d, err
+	}
+	return true, c.d, nil
+}
+
+// Close stops the background sweeper. It does not close the store.
+func (s *Server) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return nil
+}
@@ -0,0 +1,139 @@
+package pow
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer(3, time.Minute, nil)
+	defer s.Close()
+
+	challenge, err := s.Issue()
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	c, err := DecodeChallenge(challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution := c.Solve()
+
+	if good, err := s.Verify(challenge, "s.AA=="); err != nil || good {
+		t.Errorf("Verify with wrong solution = %v, %v; want false, nil", good, err)
+	}
+	if good, err := s.Verify(challenge, solution); err != nil || !good {
+		t.Errorf("Verify = %v, %v; want true, nil", good, err)
+	}
+	if _, err := s.Verify(challenge, solution); !errors.Is(err, ErrUnknownChallenge) {
+		t.Errorf("second Verify error = %v, want ErrUnknownChallenge", err)
+	}
+
+	other := GenerateChallenge(3)
+	if _, err := s.Verify(other.String(), other.Solve()); !errors.Is(err, ErrUnknownChallenge) {
+		t.Errorf("Verify of unissued challenge error = %v, want ErrUnknownChallenge", err)
+	}
+}
+
+func TestServerConcurrentVerify(t *testing.T) {
+	s := NewServer(2, time.Minute, nil)
+	defer s.Close()
+	challenge, err := s.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := DecodeChallenge(challenge)
+	solution := c.Solve()
+
+	var wg sync.WaitGroup
+	var accepted int32
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if good, _ := s.Verify(challenge, solution); good {
+				atomic.AddInt32(&accepted, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted != 1 {
+		t.Errorf("%d concurrent Verify calls succeeded, want 1", accepted)
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	defer func() { now = time.Now }()
+	start := time.Unix(1700000000, 0)
+	now = func() time.Time { return start }
+
+	m := NewMemoryStore()
+	m.Add("a", start.Add(time.Minute))
+	m.Add("b", start.Add(time.Hour))
+
+	now = func() time.Time { return start.Add(2 * time.Minute) }
+	if ok, _ := m.Contains("a"); ok {
+		t.Error("Contains reported an expired challenge")
+	}
+	if ok, _ := m.Remove("a"); ok {
+		t.Error("Remove reported an expired challenge")
+	}
+	m.Add("a", start.Add(time.Minute))
+	m.Sweep(now())
+	if len(m.expires) != 1 {
+		t.Errorf("after Sweep store holds %d challenges, want 1", len(m.expires))
+	}
+	if ok, _ := m.Contains("b"); !ok {
+		t.Error("Sweep removed an unexpired challenge")
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	s := NewServer(1, time.Minute, nil)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close failed: %v", err)
+	}
+}
+
+func TestServerAuditor(t *testing.T) {
+	var events []AuditEvent
+	s := NewServer(3, time.Minute, nil)
+	defer s.Close()
+	s.Auditor = AuditorFunc(func(e AuditEvent) { events = append(events, e) })
+
+	challenge, err := s.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := DecodeChallenge(challenge)
+	solution := c.Solve()
+	s.Verify(challenge, "s.AA==")
+	s.Verify(challenge, solution)
+	s.Verify(challenge, solution)
+
+	want := []struct {
+		typ AuditEventType
+		d   uint32
+		err error
+	}{
+		{ChallengeIssued, 3, nil},
+		{SolutionRejected, 3, nil},
+		{SolutionAccepted, 3, nil},
+		{SolutionRejected, 0, ErrUnknownChallenge},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("%d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Type != w.typ || e.Challenge != challenge || e.Difficulty != w.d || !errors.Is(e.Err, w.err) || (w.err == nil) != (e.Err == nil) || e.Time.IsZero() {
+			t.Errorf("event %d = %+v, want %v, difficulty %d, error %v", i, e, w.typ, w.d, w.err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package pow
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// Signed challenges are ordinary challenges whose value x is chosen so that
+// the issuing server can later authenticate it without keeping any state.
+// The big-endian bytes of x are laid out as
+//
+//	nonce (16 bytes) || expiry (8 bytes) || mac (32 bytes)
+//
+// where expiry is a big-endian Unix timestamp in seconds and mac is
+// HMAC-SHA256 keyed with the server secret over
+//
+//	difficulty (4 bytes, big-endian) || nonce || expiry
+//
+// Challenges signed with a context carry extra || len(extra) between the
+// nonce and the expiry, and the MAC covers it as well (see
+// GenerateChallengeWithContext). Because only x is affected, signed
+// challenges use the normal wire format and can be solved by any redpwnpow or
+// kCTF client. The expiry is carried in x rather than in a new wire version
+// for the same reason, and is covered by the MAC because a client could
+// otherwise move it.
+//
+// It follows that only VerifySignedSolution and the other functions taking
+// the key enforce the expiry. Without the key a signed value is
+// indistinguishable from a random one, so Challenge.Check and the rest of
+// the unsigned API accept solutions to expired signed challenges.
+//
+// There is deliberately no wire version with a timestamp for Check itself to
+// enforce. Without a MAC a client could rewrite the timestamp of a hoarded
+// challenge, or mint fresh challenges of its own, so an unauthenticated
+// expiry would not stop replays; and existing redpwnpow and kCTF clients
+// could not solve a new version. Servers that need expiry without state
+// issue signed challenges and verify them with VerifySignedSolution.
+const (
+	signedExpirySize = 8
+	signedMACSize    = sha256.Size
+)
+
+// SignedChallengeTTL is how long challenges from SignedChallenge remain
+// valid.
+var SignedChallengeTTL = 10 * time.Minute
+
+// SignedChallengeClockSkew is how long after their expiry signed challenges
+// are still accepted by VerifySignedSolution, to allow for clocks that differ
+// between the servers issuing and verifying them. Check does not look at the
+// expiry at all.
+var SignedChallengeClockSkew time.Duration
+
+var (
+	// ErrBadSignature is returned by VerifySignedSolution for challenges
+	// that were not issued with the given key.
+	ErrBadSignature = errors.New("challenge signature invalid")
+	// ErrExpired is returned by VerifySignedSolution for challenges whose
+	// expiry has passed.
+	ErrExpired = errors.New("challenge expired")
+)
+
+// now is replaced in tests.
+var now = time.Now
+
+// SignedChallenge creates a new random challenge authenticated with key that
+// expires after SignedChallengeTTL, and returns its encoding.
+func SignedChallenge(key []byte, d uint32) string {
+	return signedChallenge(key, d, nil)
+}
+
+// SignedChallengeWithContext is like SignedChallenge but also binds the
+// challenge to extra, which is covered by the MAC.
+func SignedChallengeWithContext(key []byte, d uint32, extra []byte) (string, error) {
+	tail, err := contextTail(extra)
+	if err != nil {
+		return "", err
+	}
+	return signedChallenge(key, d, tail), nil
+}
+
+// VerifySignedSolution decodes a challenge produced by SignedChallenge,
+// confirms that it was issued with key and has not expired, and then checks
+// solution against it.
+func VerifySignedSolution(key []byte, challenge, solution string) (bool, error) {
+	return verifySignedSolution(key, nil, challenge, solution)
+}
+
+// VerifySignedSolutionWithContext is like VerifySignedSolution for challenges
+// produced by SignedChallengeWithContext. It returns ErrBadSignature unless
+// the challenge was bound to extra.
+func VerifySignedSolutionWithContext(key, extra []byte, challenge, solution string) (bool, error) {
+	tail, err := contextTail(extra)
+	if err != nil {
+		return false, err
+	}
+	return verifySignedSolution(key, tail, challenge, solution)
+}
+
+func signedChallenge(key []byte, d uint32, tail []byte) string {
+	n := nonceSize + len(tail) + signedExpirySize
+	b := make([]byte, n+signedMACSize)
+	if _, err := rand.Read(b[:nonceSize]); err != nil {
+		panic(err)
+	}
+	copy(b[nonceSize:], tail)
+	expiry := now().Add(SignedChallengeTTL).Unix()
+	binary.BigEndian.PutUint64(b[n-signedExpirySize:], uint64(expiry))
+	copy(b[n:], signChallenge(key, d, b[:n]))
+	c := &Challenge{
+		x: NewInt(0).SetBytes(b),
+		d: d,
+	}
+	return c.String()
+}
+
+func verifySignedSolution(key, tail []byte, challenge, solution string) (bool, error) {
+	c, err := verifyAndDecode(key, tail, challenge)
+	if err != nil {
+		return false, err
+	}
+	return c.Check(solution)
+}
+
+// VerifyAndDecode decodes a challenge produced by SignedChallenge and
+// confirms that it was issued with key and has not expired, without checking
+// a solution. It returns ErrBadSignature or ErrExpired otherwise, and
+// ErrBadVersion for challenges not over ParamsP1279, which is what
+// SignedChallenge uses.
+func VerifyAndDecode(key []byte, challenge string) (*Challenge, error) {
+	return verifyAndDecode(key, nil, challenge)
+}
+
+func verifyAndDecode(key, tail []byte, challenge string) (*Challenge, error) {
+	// The MAC does not cover the version, so only the parameters signed
+	// challenges are issued with are accepted: with any other registered
+	// field the same value would make a cheaper challenge.
+	puzzle, err := ParamsP1279.Decode(challenge)
+	if err != nil {
+		return nil, err
+	}
+	c := puzzle.(*Challenge)
+	if err := c.verifySignature(key, tail); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// verifySignature checks that c is a signed challenge issued with key whose
+// nonce is followed by tail, and that it has not expired.
+func (c *Challenge) verifySignature(key, tail []byte) error {
+	n := nonceSize + len(tail) + signedExpirySize
+	b, ok := padValue(c.value(), n+signedMACSize)
+	if !ok {
+		return ErrBadSignature
+	}
+	signed, mac := b[:n], b[n:]
+	if !hmac.Equal(mac, signChallenge(key, c.d, signed)) || !bytes.Equal(b[nonceSize:n-signedExpirySize], tail) {
+		return ErrBadSignature
+	}
+	expiry := time.Unix(int64(binary.BigEndian.Uint64(b[n-signedExpirySize:])), 0)
+	if now().After(expiry.Add(SignedChallengeClockSkew)) {
+		return ErrExpired
+	}
+	return nil
+}
+
+// signChallenge computes the MAC for a signed challenge of difficulty d whose
+// value begins with signed (everything before the MAC).
+func signChallenge(key []byte, d uint32, signed []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	var dBytes [4]byte
+	binary.BigEndian.PutUint32(dBytes[:], d)
+	h.Write(dBytes[:])
+	h.Write(signed)
+	return h.Sum(nil)
+}
@@ -0,0 +1,139 @@
+package pow
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedChallenge(t *testing.T) {
+	key := []byte("secret")
+	challenge := SignedChallenge(key, 5)
+	c, err := DecodeChallenge(challenge)
+	if err != nil {
+		t.Fatalf("DecodeChallenge(%s): %v", challenge, err)
+	}
+	solution := c.Solve()
+
+	if good, err := VerifySignedSolution(key, challenge, solution); err != nil || !good {
+		t.Errorf("VerifySignedSolution = %v, %v; want true, nil", good, err)
+	}
+	if good, err := VerifySignedSolution(key, challenge, "s.AA=="); err != nil || good {
+		t.Errorf("VerifySignedSolution with wrong solution = %v, %v; want false, nil", good, err)
+	}
+	if _, err := VerifySignedSolution([]byte("other"), challenge, solution); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("VerifySignedSolution with wrong key error = %v, want ErrBadSignature", err)
+	}
+
+	// changing the difficulty must invalidate the signature
+	c.d = 4
+	if _, err := VerifySignedSolution(key, c.String(), c.Solve()); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("VerifySignedSolution with altered difficulty error = %v, want ErrBadSignature", err)
+	}
+	// so must an unsigned challenge
+	c = GenerateChallenge(5)
+	if _, err := VerifySignedSolution(key, c.String(), c.Solve()); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("VerifySignedSolution with unsigned challenge error = %v, want ErrBadSignature", err)
+	}
+}
+
+func TestSignedChallengeExpiry(t *testing.T) {
+	defer func() { now = time.Now }()
+	issued := time.Unix(1700000000, 0)
+	now = func() time.Time { return issued }
+
+	key := []byte("secret")
+	challenge := SignedChallenge(key, 1)
+	c, err := DecodeChallenge(challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution := c.Solve()
+
+	now = func() time.Time { return issued.Add(SignedChallengeTTL) }
+	if good, err := VerifySignedSolution(key, challenge, solution); err != nil || !good {
+		t.Errorf("VerifySignedSolution at expiry = %v, %v; want true, nil", good, err)
+	}
+	now = func() time.Time { return issued.Add(SignedChallengeTTL + time.Second) }
+	if _, err := VerifySignedSolution(key, challenge, solution); !errors.Is(err, ErrExpired) {
+		t.Errorf("VerifySignedSolution after expiry error = %v, want ErrExpired", err)
+	}
+	// Check has no key, so it cannot tell the challenge was signed
+	if good, err := c.Check(solution); err != nil || !good {
+		t.Errorf("Check after expiry = %v, %v; want true, nil", good, err)
+	}
+}
+
+func TestSignedChallengeClockSkew(t *testing.T) {
+	defer func() { now = time.Now }()
+	defer func(old time.Duration) { SignedChallengeClockSkew = old }(SignedChallengeClockSkew)
+	issued := time.Unix(1700000000, 0)
+	now = func() time.Time { return issued }
+
+	key := []byte("secret")
+	challenge := SignedChallenge(key, 1)
+	c, err := DecodeChallenge(challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution := c.Solve()
+
+	SignedChallengeClockSkew = time.Minute
+	now = func() time.Time { return issued.Add(SignedChallengeTTL + time.Minute) }
+	if good, err := VerifySignedSolution(key, challenge, solution); err != nil || !good {
+		t.Errorf("VerifySignedSolution within the skew = %v, %v; want true, nil", good, err)
+	}
+	now = func() time.Time { return issued.Add(SignedChallengeTTL + time.Minute + time.Second) }
+	if _, err := VerifySignedSolution(key, challenge, solution); !errors.Is(err, ErrExpired) {
+		t.Errorf("VerifySignedSolution past the skew error = %v, want ErrExpired", err)
+	}
+}
+
+func TestVerifyAndDecode(t *testing.T) {
+	defer func() { now = time.Now }()
+	issued := time.Unix(1700000000, 0)
+	now = func() time.Time { return issued }
+
+	key := []byte("secret")
+	challenge := SignedChallenge(key, 3)
+	c, err := VerifyAndDecode(key, challenge)
+	if err != nil {
+		t.Fatalf("VerifyAndDecode: %v", err)
+	}
+	if c.String() != challenge {
+		t.Errorf("VerifyAndDecode = %s, want %s", c, challenge)
+	}
+	if _, err := VerifyAndDecode([]byte("other"), challenge); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("VerifyAndDecode with another key error = %v, want ErrBadSignature", err)
+	}
+	if _, err := VerifyAndDecode(key, GenerateChallenge(3).String()); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("VerifyAndDecode of an unsigned challenge error = %v, want ErrBadSignature", err)
+	}
+	forged := &Challenge{d: 1, x: c.x}
+	if _, err := VerifyAndDecode(key, forged.String()); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("VerifyAndDecode with a lowered difficulty error = %v, want ErrBadSignature", err)
+	}
+	// Moving the value to a cheaper registered field keeps the MAC valid
+	cheap, err := NewMersenneParams("sig521", 521)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterScheme(cheap.Version, cheap); err != nil && !errors.Is(err, ErrSchemeExists) {
+		t.Fatal(err)
+	}
+	moved := "sig521" + strings.TrimPrefix(challenge, "s")
+	if _, err := DecodeChallenge(moved); err != nil {
+		t.Fatalf("DecodeChallenge(%s): %v", moved, err)
+	}
+	if _, err := VerifyAndDecode(key, moved); !errors.Is(err, ErrBadVersion) {
+		t.Errorf("VerifyAndDecode in another field error = %v, want ErrBadVersion", err)
+	}
+	if _, err := VerifySignedSolution(key, moved, "sig521.AA=="); !errors.Is(err, ErrBadVersion) {
+		t.Errorf("VerifySignedSolution in another field error = %v, want ErrBadVersion", err)
+	}
+	now = func() time.Time { return issued.Add(SignedChallengeTTL + time.Second) }
+	if _, err := VerifyAndDecode(key, challenge); !errors.Is(err, ErrExpired) {
+		t.Errorf("VerifyAndDecode after expiry error = %v, want ErrExpired", err)
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"testing"
 	"time"
-	"github.com/ncw/gmp"
 )
 
 // TestSpecificChallengePerformanceSmarter measures performance with timeout protection
 func TestSpecificChallengePerformanceSmarter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("solves a difficulty-90000 challenge")
+	}
 	challengeStr := "s.AAFfkA==.wxZVoJ86n1h9CNavECXG4w=="
 	c, err := DecodeChallenge(challengeStr)
 	if err != nil {
@@ -93,7 +95,7 @@ func TestOptimizationEffectiveness(t *testing.T) {
 	
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			c := &Challenge{d: tc.difficulty, x: gmp.NewInt(tc.value)}
+			c := &Challenge{d: tc.difficulty, x: NewInt(tc.value)}
 			
 			// Test optimized
 			start := time.Now()
@@ -152,7 +154,7 @@ func BenchmarkOptimizedVsOriginal(b *testing.B) {
 	}
 	
 	for _, scenario := range scenarios {
-		c := &Challenge{d: scenario.difficulty, x: gmp.NewInt(scenario.value)}
+		c := &Challenge{d: scenario.difficulty, x: NewInt(scenario.value)}
 		
 		b.Run(scenario.name+"_Optimized", func(b *testing.B) {
 			b.ResetTimer()
@@ -0,0 +1,59 @@
+package pow
+
+// Solution is a decoded solution proof, for callers that want to inspect or
+// log it. Check and the other verification functions take the encoded
+// string directly.
+type Solution struct {
+	y *Int    // nil means 0
+	p *Params // nil means ParamsP1279
+}
+
+// DecodeSolution decodes a solution produced by Solve. The version prefix
+// selects the parameters the solution uses.
+func DecodeSolution(s string) (*Solution, error) {
+	p, err := lookupParams(s)
+	if err != nil {
+		return nil, err
+	}
+	y, err := p.decodeSolution(s)
+	if err != nil {
+		return nil, err
+	}
+	return &Solution{y: y, p: p}, nil
+}
+
+// CanonicalSolution returns s written exactly as Solve would write it, for
+// keying solutions by string without rejecting the other encodings Check
+// accepts. It returns an error if s cannot be decoded.
+func CanonicalSolution(s string) (string, error) {
+	sol, err := DecodeSolution(s)
+	if err != nil {
+		return "", err
+	}
+	return sol.String(), nil
+}
+
+// String encodes the solution in the format produced by Solve.
+func (s *Solution) String() string {
+	return s.params().encodeSolution(s.value())
+}
+
+// ValueBytes returns the big-endian bytes of the solution value, with no
+// leading zeros.
+func (s *Solution) ValueBytes() []byte {
+	return s.value().Bytes()
+}
+
+func (s *Solution) value() *Int {
+	if s.y == nil {
+		return zero
+	}
+	return s.y
+}
+
+func (s *Solution) params() *Params {
+	if s.p == nil {
+		return ParamsP1279
+	}
+	return s.p
+}
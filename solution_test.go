@@ -0,0 +1,54 @@
+package pow
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestValueAccessors(t *testing.T) {
+	c := &Challenge{d: 3, x: NewInt(0x0102ff)}
+	if got := c.ValueHex(); got != "102ff" {
+		t.Errorf("ValueHex() = %q, want %q", got, "102ff")
+	}
+	if got := c.ValueBytes(); !bytes.Equal(got, []byte{1, 2, 0xff}) {
+		t.Errorf("ValueBytes() = %x, want 0102ff", got)
+	}
+	var zero Challenge
+	if got := zero.ValueHex(); got != "0" {
+		t.Errorf("ValueHex() of zero value = %q, want %q", got, "0")
+	}
+
+	s := c.Solve()
+	sol, err := DecodeSolution(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sol.String() != s {
+		t.Errorf("DecodeSolution(%s).String() = %s", s, sol)
+	}
+	y, _ := ParamsP1279.decodeSolution(s)
+	if !bytes.Equal(sol.ValueBytes(), y.Bytes()) {
+		t.Errorf("ValueBytes() = %x, want %x", sol.ValueBytes(), y.Bytes())
+	}
+	for _, bad := range []string{"", "x.AA==", "s.!!"} {
+		if _, err := DecodeSolution(bad); err == nil {
+			t.Errorf("DecodeSolution(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestCanonicalSolution(t *testing.T) {
+	s := GenerateChallenge(2).Solve()
+	sol, _ := DecodeSolution(s)
+	padded := "s." + base64.StdEncoding.EncodeToString(append([]byte{0}, sol.ValueBytes()...))
+	for _, in := range []string{s, " " + s + "\n", padded} {
+		if got, err := CanonicalSolution(in); got != s || err != nil {
+			t.Errorf("CanonicalSolution(%q) = %q, %v; want %q", in, got, err, s)
+		}
+	}
+	if _, err := CanonicalSolution("s.!!"); !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("CanonicalSolution of bad base64 error = %v, want ErrBadEncoding", err)
+	}
+}
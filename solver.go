@@ -0,0 +1,158 @@
+package pow
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// A Solver solves challenges one after another, reusing the same scratch
+// space for every solve instead of allocating it per call. A Solver must not
+// be used by more than one goroutine at a time; give each worker its own.
+//
+// A Solver returned by Challenge.Solver instead works through that one
+// challenge in steps, and its progress can be saved with State and restored
+// with Resume. Step panics on a Solver from NewSolver, which has no
+// challenge of its own; Remaining, Solution, State and Resume report that
+// there is nothing to continue.
+type Solver struct {
+	x *Int
+	c *Challenge // set by Challenge.Solver
+	i uint32     // iterations of c applied to x
+
+	stepped uint32        // iterations run by Step
+	elapsed time.Duration // time spent in Step
+}
+
+// ErrStateMismatch is returned by Solver.Resume for states that were not
+// saved from a solver for the same challenge.
+var ErrStateMismatch = errors.New("solver state does not match challenge")
+
+// stateFingerprintSize is the length of the challenge fingerprint at the
+// start of a saved state.
+const stateFingerprintSize = 8
+
+// NewSolver returns a new Solver.
+func NewSolver() *Solver {
+	return &Solver{x: NewInt(0)}
+}
+
+// Solve is like c.Solve but uses the solver's scratch space.
+func (s *Solver) Solve(c *Challenge) string {
+	x, _, _ := c.solveTimed(context.Background(), s.x)
+	return c.params().encodeSolution(x)
+}
+
+// AppendSolution is like Solve but appends the solution to dst and returns
+// the extended buffer. Together with the solver's scratch space this makes
+// repeated solves allocation-free with the math/big backend.
+func (s *Solver) AppendSolution(dst []byte, c *Challenge) []byte {
+	x, _, _ := c.solveTimed(context.Background(), s.x)
+	return c.params().appendSolution(dst, x)
+}
+
+// Solver returns a Solver that works through c in steps; see Step.
+func (c *Challenge) Solver() *Solver {
+	return &Solver{x: NewInt(0).Set(c.value()), c: c}
+}
+
+// Step runs up to n more iterations of the challenge and reports whether the
+// solve is complete. It may only be called on a Solver from
+// Challenge.Solver.
+func (s *Solver) Step(n uint32) bool {
+	if s.c == nil {
+		panic("pow: Solver.Step called on a Solver from NewSolver; use Challenge.Solver")
+	}
+	start := time.Now()
+	if left := s.c.d - s.i; n > left {
+		n = left
+	}
+	s.c.params().iterate(s.x, n)
+	s.i += n
+	s.stepped += n
+	s.elapsed += time.Since(start)
+	return s.i == s.c.d
+}
+
+// Rate returns the number of iterations per second measured over the calls
+// to Step so far, or 0 before any iterations have run. Time between calls is
+// not counted.
+func (s *Solver) Rate() float64 {
+	return SolveStats{Iterations: s.stepped, Elapsed: s.elapsed}.Rate()
+}
+
+// Remaining estimates how long the rest of the solve will take at the
+// current Rate. It reports false until a rate has been measured. Each
+// iteration costs the same, so a few steps are enough for a stable estimate.
+// It always reports false for a Solver from NewSolver.
+func (s *Solver) Remaining() (time.Duration, bool) {
+	r := s.Rate()
+	if r <= 0 || s.c == nil {
+		return 0, false
+	}
+	return time.Duration(float64(s.c.d-s.i) / r * float64(time.Second)), true
+}
+
+// Iterations returns the number of iterations completed so far.
+func (s *Solver) Iterations() uint32 {
+	return s.i
+}
+
+// Solution returns the solution once Step has reported the solve complete.
+// It reports false before then, and always for a Solver from NewSolver.
+func (s *Solver) Solution() (string, bool) {
+	if s.c == nil || s.i != s.c.d {
+		return "", false
+	}
+	return s.c.params().encodeSolution(s.x), true
+}
+
+// State returns the progress of the solve, for Resume to continue it later,
+// possibly in another process. The state is laid out as
+//
+//	fingerprint (8 bytes) || iterations (4 bytes, big-endian) || x
+//
+// where fingerprint is the start of the SHA-256 hash of the challenge's
+// encoding and x is the current value in big-endian bytes. It returns nil
+// for a Solver from NewSolver.
+func (s *Solver) State() []byte {
+	if s.c == nil {
+		return nil
+	}
+	return s.c.state(s.i, s.x)
+}
+
+// state encodes the progress of a solve of c that has reached x after i
+// iterations, in the layout of Solver.State.
+func (c *Challenge) state(i uint32, x *Int) []byte {
+	xb := x.Bytes()
+	b := make([]byte, stateFingerprintSize+4, stateFingerprintSize+4+len(xb))
+	copy(b, c.fingerprint())
+	binary.BigEndian.PutUint32(b[stateFingerprintSize:], i)
+	return append(b, xb...)
+}
+
+// Resume restores progress saved by State. It returns ErrStateMismatch and
+// leaves the solver unchanged if state was not saved while solving the same
+// challenge, which a Solver from NewSolver does not have.
+func (s *Solver) Resume(state []byte) error {
+	n := stateFingerprintSize + 4
+	if s.c == nil || len(state) < n || !bytes.Equal(state[:stateFingerprintSize], s.c.fingerprint()) {
+		return ErrStateMismatch
+	}
+	i := binary.BigEndian.Uint32(state[stateFingerprintSize:])
+	x := NewInt(0).SetBytes(state[n:])
+	if i > s.c.d || x.Cmp(s.c.params().Modulus) >= 0 {
+		return ErrStateMismatch
+	}
+	s.x, s.i = x, i
+	return nil
+}
+
+func (c *Challenge) fingerprint() []byte {
+	h := sha256.Sum256([]byte(c.String()))
+	return h[:stateFingerprintSize]
+}
@@ -0,0 +1,156 @@
+package pow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSolver(t *testing.T) {
+	s := NewSolver()
+	for _, c := range []*Challenge{
+		{d: 5, x: NewInt(12345)},
+		{d: 3, x: NewInt(0)},
+		{d: 2, x: NewInt(12345), p: ParamsP2203},
+		{d: 7, x: NewInt(1)},
+		GenerateChallenge(10),
+	} {
+		if got, want := s.Solve(c), c.Solve(); got != want {
+			t.Errorf("Solver.Solve(%s) = %s, want %s", c, got, want)
+		}
+	}
+}
+
+func BenchmarkSolverReuse(b *testing.B) {
+	challenges := make([]*Challenge, 16)
+	for i := range challenges {
+		challenges[i] = GenerateChallenge(2)
+	}
+	b.Run("OneShot", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			challenges[i%len(challenges)].Solve()
+		}
+	})
+	b.Run("Solver", func(b *testing.B) {
+		s := NewSolver()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.Solve(challenges[i%len(challenges)])
+		}
+	})
+}
+
+func TestSolverResume(t *testing.T) {
+	c := GenerateChallenge(25)
+	want := c.Solve()
+
+	s := c.Solver()
+	if _, ok := s.Solution(); ok {
+		t.Error("Solution reported a result before solving")
+	}
+	if s.Step(10) || s.Iterations() != 10 {
+		t.Fatalf("after Step(10): Iterations() = %d", s.Iterations())
+	}
+	state := s.State()
+
+	// continue in a fresh solver, as a restarted process would
+	resumed := c.Solver()
+	if err := resumed.Resume(state); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	for !resumed.Step(4) {
+	}
+	got, ok := resumed.Solution()
+	if !ok || got != want {
+		t.Errorf("resumed Solution() = %s, %v; want %s, true", got, ok, want)
+	}
+	if !resumed.Step(1) || resumed.Iterations() != 25 {
+		t.Errorf("Step after completion: Iterations() = %d, want 25", resumed.Iterations())
+	}
+
+	other := GenerateChallenge(25).Solver()
+	if err := other.Resume(state); err != ErrStateMismatch {
+		t.Errorf("Resume with another challenge's state: err = %v, want %v", err, ErrStateMismatch)
+	}
+	if err := c.Solver().Resume(state[:5]); err != ErrStateMismatch {
+		t.Errorf("Resume with a truncated state: err = %v, want %v", err, ErrStateMismatch)
+	}
+	bad := append([]byte(nil), state...)
+	bad[stateFingerprintSize] = 0xff
+	if err := c.Solver().Resume(bad); err != ErrStateMismatch {
+		t.Errorf("Resume past the difficulty: err = %v, want %v", err, ErrStateMismatch)
+	}
+}
+
+func TestSolverEstimate(t *testing.T) {
+	s := GenerateChallenge(1000).Solver()
+	if _, ok := s.Remaining(); ok || s.Rate() != 0 {
+		t.Errorf("estimate before stepping: Rate() = %v, Remaining() ok = %v", s.Rate(), ok)
+	}
+	s.Step(10)
+	r := s.Rate()
+	left, ok := s.Remaining()
+	if r <= 0 || !ok || left <= 0 {
+		t.Fatalf("after 10 steps: Rate() = %v, Remaining() = %v, %v", r, left, ok)
+	}
+	// 990 iterations remain
+	if want := time.Duration(990 / r * float64(time.Second)); left < want-time.Millisecond || left > want+time.Millisecond {
+		t.Errorf("Remaining() = %v, want about %v", left, want)
+	}
+}
+
+func TestSolverWithoutChallenge(t *testing.T) {
+	s := NewSolver()
+	s.Solve(GenerateChallenge(3))
+	if _, ok := s.Remaining(); ok {
+		t.Error("Remaining() reported an estimate")
+	}
+	if sol, ok := s.Solution(); ok {
+		t.Errorf("Solution() = %q, true; want false", sol)
+	}
+	if st := s.State(); st != nil {
+		t.Errorf("State() = %x, want nil", st)
+	}
+	if err := s.Resume(GenerateChallenge(3).Solver().State()); err != ErrStateMismatch {
+		t.Errorf("Resume() = %v, want %v", err, ErrStateMismatch)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Step did not panic")
+		}
+	}()
+	s.Step(1)
+}
+
+func TestAppendSolution(t *testing.T) {
+	s := NewSolver()
+	for _, c := range []*Challenge{
+		{d: 5, x: NewInt(12345)},
+		{d: 3, x: NewInt(0)},
+		{d: 2, x: NewInt(12345), p: ParamsP2203},
+		GenerateChallenge(10),
+	} {
+		want := c.Solve()
+		if got := string(c.AppendSolution([]byte("x:"))); got != "x:"+want {
+			t.Errorf("AppendSolution(%s) = %s, want x:%s", c, got, want)
+		}
+		if got := string(s.AppendSolution(nil, c)); got != want {
+			t.Errorf("Solver.AppendSolution(%s) = %s, want %s", c, got, want)
+		}
+	}
+}
+
+func TestAppendSolutionAllocs(t *testing.T) {
+	if expBackend == "gmp" {
+		t.Skip("gmp allocates when exporting values")
+	}
+	s := NewSolver()
+	c := GenerateChallenge(2)
+	buf := make([]byte, 0, 512)
+	s.AppendSolution(buf, c)
+	if n := testing.AllocsPerRun(10, func() {
+		s.AppendSolution(buf[:0], c)
+	}); n != 0 {
+		t.Errorf("Solver.AppendSolution allocates %v times per run", n)
+	}
+}
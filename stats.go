@@ -0,0 +1,112 @@
+package pow
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Stats collects observed solve times per difficulty, whether reported by
+// clients or measured by middleware from issue to verification, so that
+// operators can see what their chosen difficulties actually cost. It keeps
+// the most recent samples for each difficulty, so memory is bounded by the
+// window times the number of distinct difficulties recorded. It is safe for
+// concurrent use.
+type Stats struct {
+	window int
+
+	mu      sync.Mutex
+	samples map[uint32]*statsRing
+}
+
+// statsRing holds the last len(times) samples of one difficulty.
+type statsRing struct {
+	times []time.Duration
+	next  int    // index of the oldest sample once full
+	count uint64 // samples ever recorded
+}
+
+// DifficultyStats summarizes the samples of one difficulty in a Stats.
+// Percentiles are over the retained samples only.
+type DifficultyStats struct {
+	Difficulty    uint32
+	Count         uint64 // samples ever recorded
+	Min, Max      time.Duration
+	P50, P90, P99 time.Duration
+}
+
+// NewStats returns an empty Stats keeping the last window samples of each
+// difficulty, or 1000 if window is not positive.
+func NewStats(window int) *Stats {
+	if window <= 0 {
+		window = 1000
+	}
+	return &Stats{window: window, samples: make(map[uint32]*statsRing)}
+}
+
+// Record adds a solve of difficulty d that took elapsed.
+func (s *Stats) Record(d uint32, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.samples[d]
+	if r == nil {
+		r = &statsRing{}
+		s.samples[d] = r
+	}
+	if len(r.times) < s.window {
+		r.times = append(r.times, elapsed)
+	} else {
+		r.times[r.next] = elapsed
+		r.next = (r.next + 1) % s.window
+	}
+	r.count++
+}
+
+// RecordSolve adds the solve described by st, so that Stats can be fed
+// from SolveHook. Incomplete solves are ignored.
+func (s *Stats) RecordSolve(st SolveStats) {
+	if st.Iterations == st.Difficulty {
+		s.Record(st.Difficulty, st.Elapsed)
+	}
+}
+
+// Snapshot returns a summary of each difficulty recorded, ordered by
+// difficulty.
+func (s *Stats) Snapshot() []DifficultyStats {
+	s.mu.Lock()
+	out := make([]DifficultyStats, 0, len(s.samples))
+	var sorted []time.Duration
+	for d, r := range s.samples {
+		sorted = append(sorted[:0], r.times...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		out = append(out, DifficultyStats{
+			Difficulty: d,
+			Count:      r.count,
+			Min:        sorted[0],
+			Max:        sorted[len(sorted)-1],
+			P50:        percentile(sorted, 50),
+			P90:        percentile(sorted, 90),
+			P99:        percentile(sorted, 99),
+		})
+	}
+	s.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Difficulty < out[j].Difficulty })
+	return out
+}
+
+// Reset forgets all samples.
+func (s *Stats) Reset() {
+	s.mu.Lock()
+	s.samples = make(map[uint32]*statsRing)
+	s.mu.Unlock()
+}
+
+// percentile returns the nearest-rank pth percentile of sorted, which must
+// not be empty.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p + 99) / 100
+	if i < 1 {
+		i = 1
+	}
+	return sorted[i-1]
+}
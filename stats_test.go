@@ -0,0 +1,49 @@
+package pow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	s := NewStats(100)
+	for i := 1; i <= 100; i++ {
+		s.Record(5000, time.Duration(i)*time.Millisecond)
+	}
+	s.Record(100, time.Second)
+	s.RecordSolve(SolveStats{Difficulty: 100, Iterations: 100, Elapsed: 3 * time.Second})
+	s.RecordSolve(SolveStats{Difficulty: 100, Iterations: 40, Elapsed: time.Hour})
+
+	snap := s.Snapshot()
+	if len(snap) != 2 || snap[0].Difficulty != 100 || snap[1].Difficulty != 5000 {
+		t.Fatalf("Snapshot = %+v, want difficulties 100 and 5000", snap)
+	}
+	if got := snap[0]; got.Count != 2 || got.Min != time.Second || got.Max != 3*time.Second {
+		t.Errorf("difficulty 100 = %+v, want 2 samples from 1s to 3s", got)
+	}
+	want := DifficultyStats{
+		Difficulty: 5000,
+		Count:      100,
+		Min:        time.Millisecond,
+		Max:        100 * time.Millisecond,
+		P50:        50 * time.Millisecond,
+		P90:        90 * time.Millisecond,
+		P99:        99 * time.Millisecond,
+	}
+	if snap[1] != want {
+		t.Errorf("difficulty 5000 = %+v, want %+v", snap[1], want)
+	}
+
+	// Only the last window samples are kept
+	for i := 0; i < 100; i++ {
+		s.Record(5000, time.Second)
+	}
+	if got := s.Snapshot()[1]; got.Count != 200 || got.Min != time.Second {
+		t.Errorf("after the window filled, difficulty 5000 = %+v, want 200 samples all 1s", got)
+	}
+
+	s.Reset()
+	if snap := s.Snapshot(); len(snap) != 0 {
+		t.Errorf("Snapshot after Reset = %+v", snap)
+	}
+}
@@ -0,0 +1,41 @@
+#!/usr/bin/env python3
+# Generates kctf_vectors.txt using the sloth functions and number encoding
+# from kCTF's reference pow.py (https://github.com/google/kctf), which
+# redpwnpow is a drop-in replacement for. Each output line is a
+# "challenge solution" pair exactly as kCTF would print it.
+
+import base64
+import random
+
+VERSION = 's'
+MODULUS = 2**1279 - 1
+CHALSIZE = 2**128
+
+
+def sloth_root(x, diff, p):
+    exponent = (p + 1) // 4
+    for i in range(diff):
+        x = pow(x, exponent, p) ^ 1
+    return x
+
+
+def encode_number(num):
+    size = (num.bit_length() // 24) * 3 + 3
+    return str(base64.b64encode(num.to_bytes(size, 'big')), 'utf-8')
+
+
+def encode_challenge(arr):
+    return '.'.join([VERSION] + list(map(encode_number, arr)))
+
+
+def main():
+    rng = random.Random(1279)
+    cases = [(0, 12345), (1, 0), (1, 1), (2, 0), (3, 1), (1, 2), (7, 3)]
+    cases += [(d, rng.randrange(CHALSIZE)) for d in (1, 2, 5, 10, 31, 100, 257)]
+    for diff, x in cases:
+        y = sloth_root(x, diff, MODULUS)
+        print(encode_challenge([diff, x]), encode_challenge([y]))
+
+
+if __name__ == '__main__':
+    main()
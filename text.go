@@ -0,0 +1,73 @@
+package pow
+
+import "encoding/json"
+
+// MarshalText implements encoding.TextMarshaler using the same encoding as
+// String.
+func (c *Challenge) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using DecodeChallenge.
+func (c *Challenge) UnmarshalText(text []byte) error {
+	decoded, err := DecodeChallenge(string(text))
+	if err != nil {
+		return err
+	}
+	*c = *decoded
+	return nil
+}
+
+// Set implements flag.Value together with String, so that a *Challenge can
+// be passed to flag.Var. It decodes s with DecodeChallenge.
+func (c *Challenge) Set(s string) error {
+	return c.UnmarshalText([]byte(s))
+}
+
+// MarshalJSON implements json.Marshaler, encoding the challenge as a JSON
+// string holding its text form.
+func (c *Challenge) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler for the encoding produced by
+// MarshalJSON.
+func (c *Challenge) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return c.UnmarshalText([]byte(s))
+}
+
+// MarshalText implements encoding.TextMarshaler using the same encoding as
+// String.
+func (s *Solution) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using DecodeSolution.
+func (s *Solution) UnmarshalText(text []byte) error {
+	decoded, err := DecodeSolution(string(text))
+	if err != nil {
+		return err
+	}
+	*s = *decoded
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding the solution as a JSON
+// string holding its text form.
+func (s *Solution) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler for the encoding produced by
+// MarshalJSON.
+func (s *Solution) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	return s.UnmarshalText([]byte(str))
+}
@@ -0,0 +1,103 @@
+package pow
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestTextRoundTrip(t *testing.T) {
+	type config struct {
+		Challenge *Challenge
+	}
+	c := ParamsP2203.GenerateChallenge(42)
+	b, err := json.Marshal(config{Challenge: c})
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	if want := `{"Challenge":"` + c.String() + `"}`; string(b) != want {
+		t.Errorf("json.Marshal = %s, want %s", b, want)
+	}
+	var got config
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if got.Challenge.String() != c.String() {
+		t.Errorf("round trip = %s, want %s", got.Challenge, c)
+	}
+	if err := json.Unmarshal([]byte(`{"Challenge":"bogus"}`), &got); err == nil {
+		t.Error("json.Unmarshal accepted an invalid challenge")
+	}
+}
+
+func TestChallengeFlag(t *testing.T) {
+	c := GenerateChallenge(7)
+	var got Challenge
+	var _ flag.Value = &got
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&got, "challenge", "challenge to solve")
+	if err := fs.Parse([]string{"-challenge", c.String()}); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != c.String() {
+		t.Errorf("flag value = %s, want %s", &got, c)
+	}
+	if err := fs.Parse([]string{"-challenge", "bogus"}); err == nil {
+		t.Error("flag parsing accepted an invalid challenge")
+	}
+}
+
+func TestTextTemplate(t *testing.T) {
+	c := GenerateChallenge(7)
+	var sb strings.Builder
+	tmpl := template.Must(template.New("").Parse("curl -sSfL https://pwn.red/pow | sh -s {{.}}"))
+	if err := tmpl.Execute(&sb, c); err != nil {
+		t.Fatal(err)
+	}
+	if want := "curl -sSfL https://pwn.red/pow | sh -s " + c.String(); sb.String() != want {
+		t.Errorf("template output = %s, want %s", sb.String(), want)
+	}
+}
+
+func TestSolutionJSON(t *testing.T) {
+	type response struct {
+		Challenge *Challenge
+		Solution  *Solution
+	}
+	c := GenerateChallenge(3)
+	sol, err := DecodeSolution(c.Solve())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(response{Challenge: c, Solution: sol})
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	if want := `{"Challenge":"` + c.String() + `","Solution":"` + sol.String() + `"}`; string(b) != want {
+		t.Errorf("json.Marshal = %s, want %s", b, want)
+	}
+	var got response
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if got.Challenge.String() != c.String() || got.Solution.String() != sol.String() {
+		t.Errorf("round trip = %s %s, want %s %s", got.Challenge, got.Solution, c, sol)
+	}
+	if ok, err := got.Challenge.Check(got.Solution.String()); !ok || err != nil {
+		t.Errorf("Check after round trip = %v, %v", ok, err)
+	}
+	for _, bad := range []string{`{"Solution":"bogus"}`, `{"Solution":5}`, `{"Challenge":5}`} {
+		if err := json.Unmarshal([]byte(bad), &got); err == nil {
+			t.Errorf("json.Unmarshal(%s) succeeded", bad)
+		}
+	}
+
+	var zero Solution
+	if s := zero.String(); s != "s." {
+		t.Errorf("zero Solution encodes as %q, want %q", s, "s.")
+	}
+}
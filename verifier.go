@@ -0,0 +1,133 @@
+package pow
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+)
+
+// ErrOverloaded is returned by Verifier.Check when all of its slots are busy
+// and its queue is full.
+var ErrOverloaded = errors.New("verifier overloaded")
+
+// A Verifier bounds how many checks run at once, so that a flood of
+// expensive verifications cannot take every core. Checks beyond the limit
+// wait in a bounded queue, and are shed with ErrOverloaded once it is full.
+// It is safe for concurrent use.
+type Verifier struct {
+	// MaxDifficulty, if not zero, is the largest difficulty Check accepts,
+	// for services that know how hard the challenges they issued are.
+	// Harder challenges are rejected with ErrDifficultyTooHigh without
+	// taking a slot. MaxCheckDifficulty applies either way.
+	MaxDifficulty uint32
+
+	// Auditor, if not nil, receives an event for every solution checked,
+	// shed, or abandoned. Set it before using the verifier.
+	Auditor Auditor
+
+	slots    chan struct{}
+	maxQueue int64
+
+	queued    int64
+	checked   uint64
+	shed      uint64
+	abandoned uint64
+}
+
+// VerifierStats is a snapshot of a Verifier's load and counters.
+type VerifierStats struct {
+	Running   int    // checks in progress
+	Queued    int    // checks waiting for a slot
+	Checked   uint64 // checks run to completion, successful or not
+	Shed      uint64 // checks rejected with ErrOverloaded
+	Abandoned uint64 // checks whose context ended while queued or running
+}
+
+// NewVerifier returns a Verifier running up to maxConcurrent checks at once,
+// or GOMAXPROCS if maxConcurrent is not positive, with up to maxQueue more
+// waiting. With a maxQueue of 0, checks that find no free slot are shed
+// immediately.
+func NewVerifier(maxConcurrent, maxQueue int) *Verifier {
+	if maxConcurrent <= 0 {
+		maxConcurrent = runtime.GOMAXPROCS(0)
+	}
+	if maxQueue < 0 {
+		maxQueue = 0
+	}
+	return &Verifier{
+		slots:    make(chan struct{}, maxConcurrent),
+		maxQueue: int64(maxQueue),
+	}
+}
+
+// Check is like c.CheckContext but waits for a free slot first. It returns
+// ErrOverloaded if the queue is full, and ctx.Err() if ctx ends while
+// waiting or checking.
+func (v *Verifier) Check(ctx context.Context, c *Challenge, s string) (bool, error) {
+	ok, err := v.check(ctx, c, s)
+	if v.Auditor != nil {
+		auditCheck(v.Auditor, c.String(), c.d, ok, err)
+	}
+	return ok, err
+}
+
+func (v *Verifier) check(ctx context.Context, c *Challenge, s string) (bool, error) {
+	max := MaxCheckDifficulty
+	if v.MaxDifficulty != 0 && v.MaxDifficulty < max {
+		max = v.MaxDifficulty
+	}
+	if c.d > max {
+		return false, ErrDifficultyTooHigh
+	}
+	if err := v.acquire(ctx); err != nil {
+		return false, err
+	}
+	defer func() { <-v.slots }()
+	ok, err := c.checkContext(ctx, s, max)
+	if err != nil && err == ctx.Err() {
+		atomic.AddUint64(&v.abandoned, 1)
+	} else {
+		atomic.AddUint64(&v.checked, 1)
+	}
+	return ok, err
+}
+
+func (v *Verifier) acquire(ctx context.Context) error {
+	select {
+	case v.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if atomic.AddInt64(&v.queued, 1) > v.maxQueue {
+		atomic.AddInt64(&v.queued, -1)
+		atomic.AddUint64(&v.shed, 1)
+		return ErrOverloaded
+	}
+	defer atomic.AddInt64(&v.queued, -1)
+	select {
+	case v.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		atomic.AddUint64(&v.abandoned, 1)
+		return ctx.Err()
+	}
+}
+
+// Load returns the checks running and queued as a fraction of the
+// verifier's concurrency: 1 when every slot is busy, more when checks are
+// waiting. It suits NewDifficultyController.
+func (v *Verifier) Load() float64 {
+	return float64(len(v.slots)+int(atomic.LoadInt64(&v.queued))) / float64(cap(v.slots))
+}
+
+// Stats returns the verifier's current load and counters.
+func (v *Verifier) Stats() VerifierStats {
+	return VerifierStats{
+		Running:   len(v.slots),
+		Queued:    int(atomic.LoadInt64(&v.queued)),
+		Checked:   atomic.LoadUint64(&v.checked),
+		Shed:      atomic.LoadUint64(&v.shed),
+		Abandoned: atomic.LoadUint64(&v.abandoned),
+	}
+}
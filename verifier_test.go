@@ -0,0 +1,95 @@
+package pow
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestVerifier(t *testing.T) {
+	v := NewVerifier(1, 1)
+	c := GenerateChallenge(3)
+	s := c.Solve()
+	if ok, err := v.Check(context.Background(), c, s); err != nil || !ok {
+		t.Fatalf("Check = %v, %v, want true, nil", ok, err)
+	}
+
+	// Occupy the only slot, so the next check queues and the one after
+	// that is shed
+	v.slots <- struct{}{}
+	done := make(chan error)
+	go func() {
+		_, err := v.Check(context.Background(), c, s)
+		done <- err
+	}()
+	for v.Stats().Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := v.Check(context.Background(), c, s); err != ErrOverloaded {
+		t.Errorf("Check with full queue = %v, want ErrOverloaded", err)
+	}
+	<-v.slots
+	if err := <-done; err != nil {
+		t.Errorf("queued Check = %v", err)
+	}
+
+	// A queued check gives up when its context ends
+	v.slots <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := v.Check(ctx, c, s); err != context.Canceled {
+		t.Errorf("cancelled Check = %v, want context.Canceled", err)
+	}
+	<-v.slots
+
+	want := VerifierStats{Checked: 2, Shed: 1, Abandoned: 1}
+	if st := v.Stats(); st != want {
+		t.Errorf("Stats = %+v, want %+v", st, want)
+	}
+}
+
+func TestVerifierMaxDifficulty(t *testing.T) {
+	v := NewVerifier(1, 0)
+	v.MaxDifficulty = 10
+	// The only slot is taken, so a check that got past the limit would be
+	// shed instead
+	v.slots <- struct{}{}
+	if _, err := v.Check(context.Background(), GenerateChallenge(11), ""); err != ErrDifficultyTooHigh {
+		t.Errorf("Check above MaxDifficulty = %v, want ErrDifficultyTooHigh", err)
+	}
+	<-v.slots
+	c := GenerateChallenge(10)
+	if ok, err := v.Check(context.Background(), c, c.Solve()); err != nil || !ok {
+		t.Errorf("Check at MaxDifficulty = %v, %v; want true, nil", ok, err)
+	}
+}
+
+func TestVerifierAuditor(t *testing.T) {
+	var events []AuditEvent
+	v := NewVerifier(1, 0)
+	v.Auditor = AuditorFunc(func(e AuditEvent) { events = append(events, e) })
+	c := GenerateChallenge(3)
+	v.Check(context.Background(), c, c.Solve())
+	v.slots <- struct{}{}
+	v.Check(context.Background(), c, c.Solve())
+	<-v.slots
+
+	if len(events) != 2 || events[0].Type != SolutionAccepted || events[1].Type != SolutionRejected || events[1].Err != ErrOverloaded {
+		t.Errorf("events = %+v, want an acceptance and a rejection for overload", events)
+	}
+	if events[0].Challenge != c.String() || events[0].Difficulty != 3 {
+		t.Errorf("event = %+v, want challenge %s of difficulty 3", events[0], c)
+	}
+}
+
+func TestVerifierLoad(t *testing.T) {
+	v := NewVerifier(2, 4)
+	if l := v.Load(); l != 0 {
+		t.Errorf("idle Load = %v, want 0", l)
+	}
+	v.slots <- struct{}{}
+	v.queued = 2
+	if l := v.Load(); l != 1.5 {
+		t.Errorf("Load = %v, want 1.5", l)
+	}
+}
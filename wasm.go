@@ -0,0 +1,69 @@
+//go:build js && wasm
+
+package pow
+
+import "syscall/js"
+
+// RegisterJS installs solve, check, and decodeChallenge functions on obj for
+// use from JavaScript. All of them take and return strings in the same wire
+// format as DecodeChallenge, String, and Solve:
+//
+//	solve(challenge) -> solution
+//	check(challenge, solution) -> bool
+//	decodeChallenge(challenge) -> {difficulty, challenge}
+//
+// Failures are reported by returning an Error object instead of a result.
+func RegisterJS(obj js.Value) {
+	obj.Set("solve", js.FuncOf(jsSolve))
+	obj.Set("check", js.FuncOf(jsCheck))
+	obj.Set("decodeChallenge", js.FuncOf(jsDecodeChallenge))
+}
+
+func jsError(err error) js.Value {
+	return js.Global().Get("Error").New(err.Error())
+}
+
+func jsDecodeArg(args []js.Value, n int) (*Challenge, js.Value, bool) {
+	if len(args) < n || args[0].Type() != js.TypeString {
+		return nil, js.Global().Get("Error").New("expected challenge string argument"), false
+	}
+	c, err := DecodeChallenge(args[0].String())
+	if err != nil {
+		return nil, jsError(err), false
+	}
+	return c, js.Undefined(), true
+}
+
+func jsSolve(this js.Value, args []js.Value) interface{} {
+	c, e, ok := jsDecodeArg(args, 1)
+	if !ok {
+		return e
+	}
+	return c.Solve()
+}
+
+func jsCheck(this js.Value, args []js.Value) interface{} {
+	c, e, ok := jsDecodeArg(args, 2)
+	if !ok {
+		return e
+	}
+	if args[1].Type() != js.TypeString {
+		return js.Global().Get("Error").New("expected solution string argument")
+	}
+	good, err := c.Check(args[1].String())
+	if err != nil {
+		return jsError(err)
+	}
+	return good
+}
+
+func jsDecodeChallenge(this js.Value, args []js.Value) interface{} {
+	c, e, ok := jsDecodeArg(args, 1)
+	if !ok {
+		return e
+	}
+	return map[string]interface{}{
+		"difficulty": c.d,
+		"challenge":  c.String(),
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !js
+
+package pow
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// TestBuildJSWasm cross-compiles the JavaScript exports, which the tests on
+// the host never build otherwise. On js/wasm cgo is unavailable, so this also
+// checks that the math/big backend alone is enough for the package.
+func TestBuildJSWasm(t *testing.T) {
+	if testing.Short() {
+		t.Skip("cross-compiles the package")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	cmd := exec.Command(goBin, "build", "-o", os.DevNull, ".", "./cmd/redpwnpowjs")
+	cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("GOOS=js GOARCH=wasm go build: %v\n%s", err, out)
+	}
+}
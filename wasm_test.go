@@ -0,0 +1,32 @@
+//go:build js && wasm
+
+package pow
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+func TestRegisterJS(t *testing.T) {
+	obj := js.Global().Get("Object").New()
+	RegisterJS(obj)
+
+	c := GenerateChallenge(5)
+	s := obj.Call("solve", c.String())
+	if s.Type() != js.TypeString {
+		t.Fatalf("solve returned %v, want a string", s)
+	}
+	if good := obj.Call("check", c.String(), s.String()); !good.Bool() {
+		t.Errorf("check(%s, %s) = %v, want true", c, s, good)
+	}
+	d := obj.Call("decodeChallenge", c.String())
+	if got := d.Get("challenge").String(); got != c.String() {
+		t.Errorf("decodeChallenge challenge = %s, want %s", got, c)
+	}
+	if got := d.Get("difficulty").Int(); got != 5 {
+		t.Errorf("decodeChallenge difficulty = %d, want 5", got)
+	}
+	if e := obj.Call("solve", "bogus"); !e.InstanceOf(js.Global().Get("Error")) {
+		t.Errorf("solve(bogus) = %v, want an Error", e)
+	}
+}